@@ -0,0 +1,121 @@
+// Command roll rolls dice from the command line with the engine of the plugin, for example to test
+// expressions without a Mattermost server.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+const usageText = `Usage:
+    roll [flags] <expression>
+
+Put -- before an expression starting with '-', which would be read as a flag otherwise.
+
+Examples:
+    roll 2d6 +3
+    roll --seed 42 --json 4d6+1
+    roll --odds 3d6
+    roll -- -1 1d4
+
+Flags:
+`
+
+// jsonResult is the JSON output of a roll, in the format of the plugin's structured rolls.
+type jsonResult struct {
+	Expression string             `json:"expression"`
+	Total      int                `json:"total"`
+	Requests   []dice.RequestData `json:"requests"`
+}
+
+// jsonOdds is the JSON output of the odds of an expression.
+type jsonOdds struct {
+	Expression string    `json:"expression"`
+	Min        int       `json:"min"`
+	Max        int       `json:"max"`
+	Mean       float64   `json:"mean"`
+	Probs      []float64 `json:"probs"`
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("roll", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usageText)
+		flags.PrintDefaults()
+	}
+	seed := flags.Int64("seed", 0, "seed of the random numbers, to get reproducible rolls (random when 0)")
+	odds := flags.Bool("odds", false, "print the odds of the expression instead of rolling it")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	name := flags.String("name", "You", "name of the roller in the breakdown")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			// The usage was requested, and already printed
+			return nil
+		}
+		return err
+	}
+
+	expression, err := dice.Parse(strings.Join(flags.Args(), " "))
+	if err != nil {
+		return err
+	}
+
+	if *odds {
+		dist, err := expression.Distribution()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return writeJSON(stdout, &jsonOdds{
+				Expression: expression.Text,
+				Min:        dist.Min,
+				Max:        dist.Max(),
+				Mean:       dist.Mean(),
+				Probs:      dist.Probs,
+			})
+		}
+		_, err = fmt.Fprintf(stdout, "*%s*: %s\n", expression.Text, dist.Summary())
+		return err
+	}
+
+	var source rand.Source
+	if *seed != 0 {
+		source = rand.NewSource(*seed)
+	}
+	result := dice.NewRoller(source).Roll(expression)
+	if *asJSON {
+		return writeJSON(stdout, newJSONResult(result))
+	}
+	_, err = fmt.Fprintln(stdout, result.Breakdown(*name))
+	return err
+}
+
+func newJSONResult(result *dice.Result) *jsonResult {
+	return &jsonResult{
+		Expression: result.Expression.Text,
+		Total:      result.Total,
+		Requests:   result.Data(),
+	}
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
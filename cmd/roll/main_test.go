@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoll(t *testing.T) {
+	var stdout bytes.Buffer
+	assert.Nil(t, run([]string{"--name", "Alice", "3d1", "+2"}, &stdout, io.Discard))
+	assert.Equal(t, "**Alice** rolls `3d1 +2` = **5**\n- `3d1`: 1 1 1\n- +2\n", stdout.String())
+}
+
+func TestRollSeed(t *testing.T) {
+	var first, second bytes.Buffer
+	assert.Nil(t, run([]string{"--seed", "42", "10d20"}, &first, io.Discard))
+	assert.Nil(t, run([]string{"--seed", "42", "10d20"}, &second, io.Discard))
+	assert.Equal(t, first.String(), second.String())
+}
+
+func TestRollJSON(t *testing.T) {
+	var stdout bytes.Buffer
+	assert.Nil(t, run([]string{"--json", "2d1+1 -2"}, &stdout, io.Discard))
+	assert.JSONEq(t, `{"expression":"2d1+1 -2","total":2,"requests":[`+
+		`{"code":"2d1+1","type":"numeric","sides":1,"modifier":1,"total":4,"dice":[{"face":1,"result":2,"kept":true},{"face":1,"result":2,"kept":true}]},`+
+		`{"code":"-2","type":"sumModifier","modifier":-2,"total":-2}]}`, stdout.String())
+}
+
+func TestRollOdds(t *testing.T) {
+	var stdout bytes.Buffer
+	assert.Nil(t, run([]string{"--odds", "2d6"}, &stdout, io.Discard))
+	assert.Equal(t, "*2d6*: from 2 to 12, average 7.00, most likely 7 (16.67%)\n", stdout.String())
+
+	stdout.Reset()
+	assert.Nil(t, run([]string{"--odds", "--json", "2d1"}, &stdout, io.Discard))
+	var odds jsonOdds
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &odds))
+	assert.Equal(t, 2, odds.Min)
+	assert.Equal(t, []float64{1}, odds.Probs)
+}
+
+func TestRollHelp(t *testing.T) {
+	var stderr bytes.Buffer
+	assert.Nil(t, run([]string{"--help"}, io.Discard, &stderr))
+	assert.Contains(t, stderr.String(), "Usage:")
+}
+
+func TestRollNegativeExpression(t *testing.T) {
+	var stdout bytes.Buffer
+	assert.Nil(t, run([]string{"--", "-3", "1d1"}, &stdout, io.Discard))
+	assert.Equal(t, "**You** rolls `-3 1d1` = **-2**\n", stdout.String())
+}
+
+func TestRollErrors(t *testing.T) {
+	for _, args := range [][]string{{}, {"6d"}, {"--odds", "100d1000"}, {"--unknown", "d6"}} {
+		assert.NotNil(t, run(args, io.Discard, io.Discard), args)
+	}
+}
@@ -0,0 +1,42 @@
+package dice
+
+// RequestData is the JSON representation of the result of a roll request, such as '4d6+1' or '+3'.
+// It is shared by the integrations of the engine, so that their outputs have the same format.
+type RequestData struct {
+	Code     string    `json:"code"`
+	Type     RollType  `json:"type"`
+	Sides    int       `json:"sides,omitempty"`
+	Modifier int       `json:"modifier,omitempty"`
+	Dice     []DieData `json:"dice,omitempty"`
+	Total    int       `json:"total"`
+}
+
+// DieData is the JSON representation of a single die of a roll request.
+type DieData struct {
+	// Face is the raw result of the die, and Result the face with the modifier
+	Face   int  `json:"face"`
+	Result int  `json:"result"`
+	Kept   bool `json:"kept"`
+}
+
+// Data returns the JSON representation of the results of the requests of the expression.
+func (r *Result) Data() []RequestData {
+	requests := make([]RequestData, len(r.Requests))
+	for i, request := range r.Requests {
+		requests[i] = RequestData{
+			Code:     request.Request.Code,
+			Type:     request.Request.Type,
+			Sides:    request.Request.Sides,
+			Modifier: request.Request.Modifier,
+			Total:    request.Total,
+		}
+		if len(request.Dice) == 0 {
+			continue
+		}
+		requests[i].Dice = make([]DieData, len(request.Dice))
+		for j, die := range request.Dice {
+			requests[i].Dice[j] = DieData{Face: die.Face, Result: die.Result, Kept: die.Kept}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,325 @@
+// Package dice parses and rolls dice expressions such as '4d6+1 d20 +3', as used by the
+// /roll command of the Mattermost dice roller plugin.
+//
+// An expression is made of roll requests separated by spaces. A roll request is either:
+//   - a die code '<number of dice>d<number of sides><modifier>', such as '4d6+1': the number of
+//     dice and the modifier are optional, and the modifier is added to every die,
+//   - or a modifier, such as '+3', added to the total.
+//
+// A modifier can chain several terms, which can be variables resolved when parsing the
+// expression, such as '1d20+@dex+@prof' or '-@penalty+1'.
+//
+// The 'sum' keyword of previous versions is ignored.
+package dice
+
+import (
+	"errors"
+	"strconv"
+	"unicode"
+)
+
+// RollType list the kinds of roll requests.
+type RollType string
+
+const (
+	// Numeric is a roll request rolling dice, such as '4d6+1'.
+	Numeric RollType = "numeric"
+	// SumModifier is a roll request adding a modifier to the total, such as '+3'.
+	SumModifier RollType = "sumModifier"
+)
+
+// MaxDice is the maximum number of dice of a roll request.
+const MaxDice int = 100
+
+// Request is a parsed roll request.
+type Request struct {
+	// Code is the text of the roll request
+	Code string
+	Type RollType
+	// Number and Sides describe the dice of a numeric request
+	Number int
+	Sides  int
+	// Modifier is added to every die of a numeric request, or to the total for a sum modifier
+	Modifier int
+}
+
+// Expression is a parsed dice expression.
+type Expression struct {
+	Text     string
+	Requests []Request
+}
+
+// VariableResolver returns the value of a variable used in an expression, such as '@dex'
+// (without '@'), or false when the variable is undefined.
+type VariableResolver func(name string) (int, bool)
+
+// Parse parses an expression made of roll requests separated by spaces.
+// The errors of invalid roll requests are returned as a *ParseError locating them in the text.
+func Parse(text string) (*Expression, error) {
+	return ParseWithVariables(text, nil)
+}
+
+// ParseWithVariables parses an expression whose modifiers may use variables, such as
+// '1d20+@dex+@prof', resolving them with the given resolver.
+func ParseWithVariables(text string, variables VariableResolver) (*Expression, error) {
+	expression := &Expression{Text: text}
+	for _, field := range splitFields(text) {
+		// Ignore the 'sum' keyword, remnant of a previous version
+		if field.code == "sum" {
+			continue
+		}
+		request, err := parseRequest(field.code, variables)
+		if err != nil {
+			parseErr := &ParseError{
+				Text:     text,
+				Code:     field.code,
+				Offset:   field.offset,
+				Position: field.offset,
+				Length:   len(field.code),
+				Err:      err,
+			}
+			var syntaxErr *SyntaxError
+			var variableErr *UndefinedVariableError
+			if errors.As(err, &syntaxErr) {
+				parseErr.Position += syntaxErr.Position
+				// The error can be at the end of the code, when it is missing a part
+				parseErr.Length = min(1, len(field.code)-syntaxErr.Position)
+			} else if errors.As(err, &variableErr) {
+				parseErr.Position += variableErr.Position
+				parseErr.Length = len(variableErr.Name) + 1
+			}
+			return nil, parseErr
+		}
+		expression.Requests = append(expression.Requests, *request)
+	}
+	if len(expression.Requests) == 0 {
+		return nil, ErrNoRequest
+	}
+	return expression, nil
+}
+
+// field is a roll request of an expression, with its byte offset in the expression.
+type field struct {
+	code   string
+	offset int
+}
+
+// splitFields splits the text around spaces like strings.Fields, keeping the offsets of the fields.
+func splitFields(text string) []field {
+	fields := []field{}
+	start := -1
+	for i, char := range text {
+		switch {
+		case unicode.IsSpace(char) && start >= 0:
+			fields = append(fields, field{code: text[start:i], offset: start})
+			start = -1
+		case !unicode.IsSpace(char) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, field{code: text[start:], offset: start})
+	}
+	return fields
+}
+
+// ParseRequest parses a single roll request without variables, such as '4d6+1' or '+3'.
+func ParseRequest(code string) (*Request, error) {
+	return parseRequest(code, nil)
+}
+
+func parseRequest(code string, variables VariableResolver) (*Request, error) {
+	scanned, err := scanRequest(code)
+	if err != nil {
+		return nil, err
+	}
+
+	modifier := 0
+	for _, term := range scanned.modifiers {
+		value, err := term.value(variables)
+		if err != nil {
+			return nil, err
+		}
+		modifier += value
+	}
+
+	if scanned.sides == "" {
+		return &Request{Code: code, Type: SumModifier, Modifier: modifier}, nil
+	}
+
+	number := 1
+	if scanned.number != "" {
+		number, err = strconv.Atoi(scanned.number)
+		if err != nil {
+			return nil, &NumberError{Kind: "number of dice", Value: scanned.number}
+		}
+		if number > MaxDice {
+			// Complain about insanity.
+			return nil, &TooManyDiceError{Number: number}
+		}
+	}
+
+	sides, err := strconv.Atoi(scanned.sides)
+	if err != nil {
+		return nil, &NumberError{Kind: "number of sides", Value: scanned.sides}
+	}
+
+	return &Request{Code: code, Type: Numeric, Number: number, Sides: sides, Modifier: modifier}, nil
+}
+
+// Tokens expected by the syntax errors.
+const (
+	expectedNumberOfDice  = "a number of dice"
+	expectedD             = "'d'"
+	expectedNumberOfSides = "a number of sides"
+	expectedModifier      = "a modifier such as '+1'"
+	expectedDigit         = "a digit"
+	expectedVariable      = "a variable such as '@dex'"
+	expectedVariableName  = "a variable name"
+)
+
+// scannedRequest holds the parts of a roll request: a sum modifier only has modifiers.
+type scannedRequest struct {
+	number, sides string
+	modifiers     []modifierTerm
+}
+
+// modifierTerm is a term of a modifier, such as '+3' or '-@dex'.
+type modifierTerm struct {
+	negative bool
+	// digits is the number of the term, or variable the name of its variable
+	digits   string
+	variable string
+	// position is the byte offset of the variable in the roll request, starting with '@'
+	position int
+}
+
+func (t *modifierTerm) value(variables VariableResolver) (int, error) {
+	var value int
+	if t.variable != "" {
+		var ok bool
+		if variables != nil {
+			value, ok = variables(t.variable)
+		}
+		if !ok {
+			return 0, &UndefinedVariableError{Name: t.variable, Position: t.position}
+		}
+	} else {
+		var err error
+		if value, err = strconv.Atoi(t.digits); err != nil {
+			return 0, &NumberError{Kind: "modifier", Value: t.digits}
+		}
+	}
+	if t.negative {
+		return -value, nil
+	}
+	return value, nil
+}
+
+// isVariableChar tells whether a character can be used in a variable name: lowercase letters,
+// underscores, and digits after the first character.
+func isVariableChar(char byte, first bool) bool {
+	return (char >= 'a' && char <= 'z') || char == '_' || (!first && char >= '0' && char <= '9')
+}
+
+// scanRequest splits a roll request into its parts, following the syntax
+// '<optional number of dice><optional 'd' or 'D'><number of sides><optional modifiers>'
+// or '<modifiers>', where the modifiers are terms such as '+3' or '-@dex'.
+// It returns a *SyntaxError at the first unexpected character.
+func scanRequest(code string) (*scannedRequest, error) {
+	syntaxError := func(position int, expected ...string) error {
+		return &SyntaxError{Code: code, Position: position, Expected: expected}
+	}
+	// digits returns the end of the digits starting at the given position
+	digits := func(start int) int {
+		end := start
+		for end < len(code) && code[end] >= '0' && code[end] <= '9' {
+			end++
+		}
+		return end
+	}
+	// modifiers scans the modifier terms starting at the given position, up to the end of the code
+	modifiers := func(start int, expected ...string) ([]modifierTerm, error) {
+		terms := []modifierTerm{}
+		position := start
+		for position < len(code) {
+			term := modifierTerm{}
+			switch {
+			case code[position] == '+':
+				position++
+			case code[position] == '-':
+				term.negative = true
+				position++
+			case position == 0 && code[position] == '@':
+				// A variable alone is added to the total
+			default:
+				return nil, syntaxError(position, append(expected, expectedModifier)...)
+			}
+
+			switch {
+			case position < len(code) && code[position] == '@':
+				end := position + 1
+				for end < len(code) && isVariableChar(code[end], end == position+1) {
+					end++
+				}
+				if end == position+1 {
+					return nil, syntaxError(end, expectedVariableName)
+				}
+				term.variable = code[position+1 : end]
+				term.position = position
+				position = end
+				expected = []string{}
+			default:
+				end := digits(position)
+				if end == position {
+					return nil, syntaxError(position, expectedDigit, expectedVariable)
+				}
+				term.digits = code[position:end]
+				position = end
+				expected = []string{expectedDigit}
+			}
+			terms = append(terms, term)
+		}
+		return terms, nil
+	}
+
+	if code != "" && (code[0] == '+' || code[0] == '-' || code[0] == '@') {
+		sumModifiers, err := modifiers(0)
+		if err != nil {
+			return nil, err
+		}
+		return &scannedRequest{modifiers: sumModifiers}, nil
+	}
+
+	scanned := &scannedRequest{}
+	position := 0
+	if position < len(code) && code[position] >= '1' && code[position] <= '9' {
+		end := digits(position)
+		if end == len(code) || (code[end] != 'd' && code[end] != 'D') {
+			// A number without 'd' is the number of sides of a single die
+			scanned.sides = code[position:end]
+			var err error
+			if scanned.modifiers, err = modifiers(end, expectedDigit); err != nil {
+				return nil, err
+			}
+			return scanned, nil
+		}
+		scanned.number = code[position:end]
+		position = end
+	}
+	if position == len(code) || (code[position] != 'd' && code[position] != 'D') {
+		return nil, syntaxError(position, expectedNumberOfDice, expectedD, expectedModifier)
+	}
+	position++
+
+	if position == len(code) || code[position] < '1' || code[position] > '9' {
+		return nil, syntaxError(position, expectedNumberOfSides)
+	}
+	end := digits(position)
+	scanned.sides = code[position:end]
+	var err error
+	if scanned.modifiers, err = modifiers(end, expectedDigit); err != nil {
+		return nil, err
+	}
+	return scanned, nil
+}
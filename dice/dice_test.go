@@ -0,0 +1,253 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRange(t *testing.T) {
+	res, err := Roll("1000d20")
+	assert.NotNil(t, err)
+	assert.Nil(t, res)
+}
+
+func TestRange1(t *testing.T) {
+	res, err := Roll("1000d1")
+	assert.NotNil(t, err)
+	assert.Nil(t, res)
+}
+
+func TestRange2(t *testing.T) {
+	res, err := Roll("10d20")
+	assert.Nil(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, 10, len(res.Requests[0].Dice))
+	for _, die := range res.Requests[0].Dice {
+		if die.Result <= 0 || die.Result > 20 {
+			t.Errorf("Value '%d' is not valid for a D20 roll", die.Result)
+		}
+	}
+}
+
+func TestRange3(t *testing.T) {
+	res, err := Roll("10d1")
+	assert.Nil(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, 10, len(res.Requests[0].Dice))
+	for _, die := range res.Requests[0].Dice {
+		if die.Result != 1 {
+			t.Errorf("Value '%d' is not valid for a D1 roll", die.Result)
+		}
+	}
+}
+
+func TestDieCodes(t *testing.T) {
+	testCases := []struct {
+		code   string
+		sides  int
+		number int
+	}{
+		{code: "d20", sides: 20, number: 1},
+		{code: "5d20", sides: 20, number: 5},
+		{code: "20D1", sides: 1, number: 20},
+		{code: "1", sides: 1, number: 1},
+		{code: "12", sides: 12, number: 1},
+		{code: "D1000", sides: 1000, number: 1},
+		{code: fmt.Sprintf("%dD10", MaxDice), sides: 10, number: MaxDice},
+	}
+	for _, testCase := range testCases {
+		res, err := Roll(testCase.code)
+		assert.Nil(t, err, testCase.code)
+		assert.NotNil(t, res, testCase.code)
+		assert.Equal(t, testCase.sides, res.Requests[0].Request.Sides, testCase.code)
+		assert.Equal(t, testCase.number, len(res.Requests[0].Dice), testCase.code)
+	}
+}
+
+func TestModifiersOK(t *testing.T) {
+	testCases := []struct {
+		dice           string
+		resultType     RollType
+		comparisonType string
+		compareValue   int
+	}{
+		{dice: "20+100", resultType: Numeric, comparisonType: "greater", compareValue: 100},
+		{dice: "2D6+10", resultType: Numeric, comparisonType: "greater", compareValue: 10},
+		{dice: "1+0", resultType: Numeric, comparisonType: "equal", compareValue: 1},
+		{dice: "d6-100", resultType: Numeric, comparisonType: "lesser", compareValue: -93},
+		{dice: "+10", resultType: SumModifier, comparisonType: "equals", compareValue: 10},
+		{dice: "-42", resultType: SumModifier, comparisonType: "equals", compareValue: -42},
+		{dice: "+42", resultType: SumModifier, comparisonType: "equals", compareValue: 42},
+	}
+	for _, testCase := range testCases {
+		res, err := Roll(testCase.dice)
+		message := "Testing case " + testCase.dice
+		assert.Nil(t, err, message)
+		assert.NotNil(t, res, message)
+
+		request := res.Requests[0]
+		assert.Equal(t, testCase.resultType, request.Request.Type, message)
+		if testCase.resultType == Numeric {
+			assert.GreaterOrEqual(t, len(request.Dice), 1, message)
+			for _, die := range request.Dice {
+				switch testCase.comparisonType {
+				case "equal":
+					assert.Equal(t, testCase.compareValue, die.Result, message)
+				case "lesser":
+					assert.Less(t, die.Result, testCase.compareValue, message)
+				case "greater":
+					assert.Greater(t, die.Result, testCase.compareValue, message)
+				}
+			}
+		} else {
+			assert.Empty(t, request.Dice, message)
+			assert.Equal(t, testCase.compareValue, request.Total, message)
+		}
+	}
+}
+
+func TestBadInputs(t *testing.T) {
+	for _, badInput := range []string{"+HAHAH", "+-5", "+haha", "D", "18D", "D=hahaha"} {
+		res, err := Roll(badInput)
+		assert.NotNil(t, err, "Testing "+badInput)
+		assert.Nil(t, res, "Testing "+badInput)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse(" sum ")
+	assert.ErrorIs(t, err, ErrNoRequest)
+
+	_, err = Parse(fmt.Sprintf("d6 %dD10", MaxDice+1))
+	var tooManyErr *TooManyDiceError
+	assert.True(t, errors.As(err, &tooManyErr))
+	assert.Equal(t, "'101' is too many dice; maximum is 100", err.Error())
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 3, parseErr.Position)
+	assert.Equal(t, 6, parseErr.Length)
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	testCases := []struct {
+		text     string
+		position int
+		expected []string
+	}{
+		{text: "2d6  6d", position: 7, expected: []string{expectedNumberOfSides}},
+		{text: "d0", position: 1, expected: []string{expectedNumberOfSides}},
+		{text: "0d5", position: 0, expected: []string{expectedNumberOfDice, expectedD, expectedModifier}},
+		{text: "hahaha", position: 0, expected: []string{expectedNumberOfDice, expectedD, expectedModifier}},
+		{text: "+-5", position: 1, expected: []string{expectedDigit, expectedVariable}},
+		{text: "d20 2x6", position: 5, expected: []string{expectedDigit, expectedModifier}},
+		{text: "2d6+", position: 4, expected: []string{expectedDigit, expectedVariable}},
+		{text: "2d6+1d", position: 5, expected: []string{expectedDigit, expectedModifier}},
+		{text: "d20+@", position: 5, expected: []string{expectedVariableName}},
+		{text: "d20+@dex!", position: 8, expected: []string{expectedModifier}},
+		{text: "D=hahaha", position: 1, expected: []string{expectedNumberOfSides}},
+	}
+	for _, testCase := range testCases {
+		_, err := Parse(testCase.text)
+		var parseErr *ParseError
+		assert.True(t, errors.As(err, &parseErr), testCase.text)
+		assert.Equal(t, testCase.position, parseErr.Position, testCase.text)
+		var syntaxErr *SyntaxError
+		assert.True(t, errors.As(err, &syntaxErr), testCase.text)
+		assert.Equal(t, testCase.expected, syntaxErr.Expected, testCase.text)
+	}
+
+	_, err := Parse("2d6 6d")
+	assert.Equal(t, "'6d' is not a valid die code: expected a number of sides", err.Error())
+}
+
+func TestParse(t *testing.T) {
+	expression, err := Parse("2d6+1 sum -3")
+	assert.Nil(t, err)
+	assert.Equal(t, []Request{
+		{Code: "2d6+1", Type: Numeric, Number: 2, Sides: 6, Modifier: 1},
+		{Code: "-3", Type: SumModifier, Modifier: -3},
+	}, expression.Requests)
+}
+
+func TestVariables(t *testing.T) {
+	variables := func(name string) (int, bool) {
+		value, ok := map[string]int{"dex": 3, "prof": 2, "str_2": -1}[name]
+		return value, ok
+	}
+	expression, err := ParseWithVariables("1d20+@dex+@prof 2d6-1+@str_2 @dex -@prof+1", variables)
+	assert.Nil(t, err)
+	assert.Equal(t, []Request{
+		{Code: "1d20+@dex+@prof", Type: Numeric, Number: 1, Sides: 20, Modifier: 5},
+		{Code: "2d6-1+@str_2", Type: Numeric, Number: 2, Sides: 6, Modifier: -2},
+		{Code: "@dex", Type: SumModifier, Modifier: 3},
+		{Code: "-@prof+1", Type: SumModifier, Modifier: -1},
+	}, expression.Requests)
+
+	_, err = ParseWithVariables("d20 1d20+@dex+@wis", variables)
+	var variableErr *UndefinedVariableError
+	assert.True(t, errors.As(err, &variableErr))
+	assert.Equal(t, "wis", variableErr.Name)
+	assert.Equal(t, "the variable '@wis' is not defined", err.Error())
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 14, parseErr.Position)
+	assert.Equal(t, 4, parseErr.Length)
+
+	_, err = Parse("d20+@dex")
+	assert.True(t, errors.As(err, &variableErr))
+}
+
+func TestChainedModifiers(t *testing.T) {
+	expression, err := Parse("2d6+1+2-4 +1-3")
+	assert.Nil(t, err)
+	assert.Equal(t, -1, expression.Requests[0].Modifier)
+	assert.Equal(t, -2, expression.Requests[1].Modifier)
+}
+
+func TestRollerSeed(t *testing.T) {
+	expression, err := Parse("10d20 +3")
+	assert.Nil(t, err)
+	first := NewRoller(rand.NewSource(42)).Roll(expression)
+	second := NewRoller(rand.NewSource(42)).Roll(expression)
+	assert.Equal(t, first, second)
+}
+
+func TestDetails(t *testing.T) {
+	res, err := Roll("3d1+1 -2")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, res.Total)
+	assert.True(t, res.HasDetails())
+	assert.Equal(t, []string{"`3d1+1`: 2 2 2", "-2"}, res.Details())
+	assert.Equal(t, []Die{{Face: 1, Result: 2, Kept: true}, {Face: 1, Result: 2, Kept: true}, {Face: 1, Result: 2, Kept: true}}, res.Requests[0].Dice)
+
+	res, err = Roll("d1 +5")
+	assert.Nil(t, err)
+	assert.False(t, res.HasDetails())
+}
+
+func TestBreakdown(t *testing.T) {
+	res, err := Roll("3d1 +2")
+	assert.Nil(t, err)
+	assert.Equal(t, "**User** rolls `3d1 +2` = **5**\n- `3d1`: 1 1 1\n- +2", res.Breakdown("User"))
+
+	res, err = Roll("d1")
+	assert.Nil(t, err)
+	assert.Equal(t, "**User** rolls `d1` = **1**", res.Breakdown("User"))
+}
+
+func TestData(t *testing.T) {
+	res, err := Roll("2d1+1 -2")
+	assert.Nil(t, err)
+	data := res.Data()
+	assert.Len(t, data, 2)
+	assert.Equal(t, []DieData{{Face: 1, Result: 2, Kept: true}, {Face: 1, Result: 2, Kept: true}}, data[0].Dice)
+	assert.Equal(t, 1, data[0].Sides)
+	assert.Equal(t, 1, data[0].Modifier)
+	assert.Equal(t, 4, data[0].Total)
+	assert.Nil(t, data[1].Dice)
+	assert.Equal(t, -2, data[1].Total)
+}
@@ -0,0 +1,108 @@
+package dice
+
+// MaxOutcomes limits the number of distinct totals of an expression whose distribution
+// can be computed, to keep the computation reasonable.
+const MaxOutcomes int = 10000
+
+// Distribution holds the probability of every possible total of an expression.
+type Distribution struct {
+	Min int
+	// Probs[i] is the probability of rolling a total of Min+i
+	Probs []float64
+}
+
+// Max returns the highest possible total.
+func (d *Distribution) Max() int {
+	return d.Min + len(d.Probs) - 1
+}
+
+// Mean returns the expected total.
+func (d *Distribution) Mean() float64 {
+	mean := 0.0
+	for i, prob := range d.Probs {
+		mean += float64(d.Min+i) * prob
+	}
+	return mean
+}
+
+// MostLikely returns the lowest of the most likely totals, and its probability.
+func (d *Distribution) MostLikely() (int, float64) {
+	best := 0
+	for i, prob := range d.Probs {
+		if prob > d.Probs[best] {
+			best = i
+		}
+	}
+	return d.Min + best, d.Probs[best]
+}
+
+// Cumulative returns the probability of rolling a total lower than or equal to the given one.
+func (d *Distribution) Cumulative(total int) float64 {
+	result := 0.0
+	for i := 0; i < len(d.Probs) && d.Min+i <= total; i++ {
+		result += d.Probs[i]
+	}
+	return result
+}
+
+// add returns the distribution of the sum of two independent rolls.
+func (d *Distribution) add(other *Distribution) *Distribution {
+	probs := make([]float64, len(d.Probs)+len(other.Probs)-1)
+	for i, prob := range d.Probs {
+		if prob == 0 {
+			continue
+		}
+		for j, otherProb := range other.Probs {
+			probs[i+j] += prob * otherProb
+		}
+	}
+	return &Distribution{Min: d.Min + other.Min, Probs: probs}
+}
+
+// DieDistribution returns the distribution of a single die with a modifier.
+func DieDistribution(sides, modifier int) *Distribution {
+	probs := make([]float64, sides)
+	for i := range probs {
+		probs[i] = 1 / float64(sides)
+	}
+	return &Distribution{Min: 1 + modifier, Probs: probs}
+}
+
+// Distribution computes the exact distribution of the total of the expression.
+// It returns a TooManyOutcomesError if the expression has more than MaxOutcomes possible totals.
+func (e *Expression) Distribution() (*Distribution, error) {
+	result := &Distribution{Min: 0, Probs: []float64{1}}
+	for _, request := range e.Requests {
+		if request.Type == SumModifier {
+			result.Min += request.Modifier
+			continue
+		}
+		// Check the size before building the die, whose distribution is as large as its sides
+		if request.Sides > MaxOutcomes || len(result.Probs)+request.Number*(request.Sides-1) > MaxOutcomes {
+			return nil, &TooManyOutcomesError{}
+		}
+		die := DieDistribution(request.Sides, request.Modifier)
+		for i := 0; i < request.Number; i++ {
+			result = result.add(die)
+		}
+	}
+	return result, nil
+}
+
+// Bounds returns the minimum, maximum and mean totals of the expression,
+// without computing its whole distribution.
+func (e *Expression) Bounds() (int, int, float64) {
+	minimum, maximum, mean := 0, 0, 0.0
+	for _, request := range e.Requests {
+		if request.Type == SumModifier {
+			minimum += request.Modifier
+			maximum += request.Modifier
+			mean += float64(request.Modifier)
+			continue
+		}
+		minimum += request.Number * (1 + request.Modifier)
+		maximum += request.Number * (request.Sides + request.Modifier)
+		mean += float64(request.Number) * (float64(request.Sides+1)/2 + float64(request.Modifier))
+	}
+	return minimum, maximum, mean
+}
@@ -0,0 +1,81 @@
+package dice
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistribution2d6(t *testing.T) {
+	expression, err := Parse("2d6")
+	assert.Nil(t, err)
+	dist, err := expression.Distribution()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, dist.Min)
+	assert.Equal(t, 12, dist.Max())
+	assert.InDelta(t, 7, dist.Mean(), 0.0001)
+	mostLikely, prob := dist.MostLikely()
+	assert.Equal(t, 7, mostLikely)
+	assert.InDelta(t, 6.0/36, prob, 0.0001)
+	assert.InDelta(t, 0, dist.Cumulative(1), 0.0001)
+	assert.InDelta(t, 21.0/36, dist.Cumulative(7), 0.0001)
+	assert.InDelta(t, 1, dist.Cumulative(12), 0.0001)
+}
+
+func TestDistributionModifiers(t *testing.T) {
+	expression, err := Parse("2d4+1 -3 sum")
+	assert.Nil(t, err)
+	dist, err := expression.Distribution()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, dist.Min)
+	assert.Equal(t, 7, dist.Max())
+	assert.InDelta(t, 4, dist.Mean(), 0.0001)
+	total := 0.0
+	for _, prob := range dist.Probs {
+		total += prob
+	}
+	assert.InDelta(t, 1, total, 0.0001)
+}
+
+func TestBounds(t *testing.T) {
+	expression, err := Parse("3d6 2d4-1 +2")
+	assert.Nil(t, err)
+	minimum, maximum, mean := expression.Bounds()
+	assert.Equal(t, 5, minimum)
+	assert.Equal(t, 26, maximum)
+	assert.InDelta(t, 15.5, mean, 0.0001)
+}
+
+func TestDistributionTooManyOutcomes(t *testing.T) {
+	expression, err := Parse("100d1000")
+	assert.Nil(t, err)
+	dist, err := expression.Distribution()
+	var tooManyErr *TooManyOutcomesError
+	assert.True(t, errors.As(err, &tooManyErr))
+	assert.Nil(t, dist)
+}
+
+func TestDistributionHugeDie(t *testing.T) {
+	for _, code := range []string{"d2000000000", "d10001", "100d9223372036854775807"} {
+		expression, err := Parse(code)
+		assert.Nil(t, err, code)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		dist, err := expression.Distribution()
+		runtime.ReadMemStats(&after)
+		var tooManyErr *TooManyOutcomesError
+		assert.True(t, errors.As(err, &tooManyErr), code)
+		assert.Nil(t, dist, code)
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20), code)
+	}
+}
+
+func TestDistributionSummary(t *testing.T) {
+	expression, err := Parse("2d6")
+	assert.Nil(t, err)
+	dist, err := expression.Distribution()
+	assert.Nil(t, err)
+	assert.Equal(t, "from 2 to 12, average 7.00, most likely 7 (16.67%)", dist.Summary())
+}
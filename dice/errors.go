@@ -0,0 +1,87 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoRequest is returned when parsing an expression without any roll request.
+var ErrNoRequest = errors.New("no roll request arguments found (such as '20', '4d6', etc.)")
+
+// ParseError is returned when parsing an expression with an invalid roll request.
+// It locates the error in the expression and wraps the error of the roll request.
+type ParseError struct {
+	Text string
+	// Code is the invalid roll request, found at the byte offset Offset of the text
+	Code   string
+	Offset int
+	// Position is the byte offset of the error in the text, and Length its number of bytes
+	Position int
+	Length   int
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// SyntaxError is returned when a roll request is neither a die code nor a modifier.
+type SyntaxError struct {
+	Code string
+	// Position is the byte offset of the first unexpected character in the code
+	Position int
+	// Expected describes the tokens that would have been valid at the position
+	Expected []string
+}
+
+func (e *SyntaxError) Error() string {
+	if len(e.Expected) == 0 {
+		return fmt.Sprintf("'%s' is not a valid die code", e.Code)
+	}
+	return fmt.Sprintf("'%s' is not a valid die code: expected %s", e.Code, strings.Join(e.Expected, " or "))
+}
+
+// NumberError is returned when a number of a roll request cannot be read, such as a number
+// too large to be represented.
+type NumberError struct {
+	// Kind is what the number stands for: "number of dice", "number of sides" or "modifier"
+	Kind  string
+	Value string
+}
+
+func (e *NumberError) Error() string {
+	return fmt.Sprintf("could not parse a %s from '%s'", e.Kind, e.Value)
+}
+
+// UndefinedVariableError is returned when an expression uses a variable which is not defined.
+type UndefinedVariableError struct {
+	Name string
+	// Position is the byte offset of the variable in the roll request, starting with '@'
+	Position int
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("the variable '@%s' is not defined", e.Name)
+}
+
+// TooManyDiceError is returned when a roll request has more than MaxDice dice.
+type TooManyDiceError struct {
+	Number int
+}
+
+func (e *TooManyDiceError) Error() string {
+	return fmt.Sprintf("'%d' is too many dice; maximum is %d", e.Number, MaxDice)
+}
+
+// TooManyOutcomesError is returned when the distribution of an expression has more than
+// MaxOutcomes possible totals.
+type TooManyOutcomesError struct{}
+
+func (e *TooManyOutcomesError) Error() string {
+	return fmt.Sprintf("there are too many possible results to compute the odds; maximum is %d", MaxOutcomes)
+}
@@ -0,0 +1,43 @@
+package dice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Breakdown formats the result as posted by the dice bot: a Markdown headline with the total,
+// followed by the result of every request when more than one die was rolled.
+func (r *Result) Breakdown(name string) string {
+	text := fmt.Sprintf("**%s** rolls %s = **%d**", name, Code(r.Expression.Text), r.Total)
+	if r.HasDetails() {
+		text += "\n- " + strings.Join(r.Details(), "\n- ")
+	}
+	return text
+}
+
+// Details formats the results of every request of the expression in Markdown, such as
+// '`4d6+1`: 3 5 2 7' or '+3'.
+func (r *Result) Details() []string {
+	details := make([]string, len(r.Requests))
+	for i, request := range r.Requests {
+		if request.Request.Type == SumModifier {
+			details[i] = request.String()
+			continue
+		}
+		details[i] = fmt.Sprintf("%s: %s", Code(request.Request.Code), request.diceResults())
+	}
+	return details
+}
+
+// Code formats an expression as Markdown inline code, so that its variables, such as '@dex',
+// are not mistaken for mentions.
+func Code(text string) string {
+	return "`" + text + "`"
+}
+
+// Summary describes the range and the most likely total of the distribution.
+func (d *Distribution) Summary() string {
+	mostLikely, mostLikelyProb := d.MostLikely()
+	return fmt.Sprintf("from %d to %d, average %.2f, most likely %d (%.2f%%)",
+		d.Min, d.Max(), d.Mean(), mostLikely, 100*mostLikelyProb)
+}
@@ -0,0 +1,117 @@
+package dice
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Die is a single rolled die.
+type Die struct {
+	// Face is the raw result of the die, and Result the face with the modifier of the request
+	Face   int
+	Result int
+	// Kept is false for the dice which do not count in the total
+	Kept bool
+}
+
+// RequestResult is the result of a roll request.
+type RequestResult struct {
+	Request Request
+	// Dice are the rolled dice of a numeric request
+	Dice  []Die
+	Total int
+}
+
+// Result is the result of an expression.
+type Result struct {
+	Expression *Expression
+	Requests   []RequestResult
+	Total      int
+}
+
+// Roller rolls expressions using a source of random numbers.
+// The zero Roller uses the global source of math/rand, and is safe for concurrent use.
+type Roller struct {
+	rand *rand.Rand
+}
+
+// NewRoller creates a roller using the given source, for example to get reproducible rolls
+// with a seeded source. A Roller created with a source is not safe for concurrent use.
+func NewRoller(source rand.Source) *Roller {
+	if source == nil {
+		return &Roller{}
+	}
+	return &Roller{rand: rand.New(source)} //nolint:gosec
+}
+
+var defaultRoller = &Roller{}
+
+// Roll parses and rolls an expression.
+func Roll(text string) (*Result, error) {
+	expression, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return defaultRoller.Roll(expression), nil
+}
+
+// Roll rolls all the requests of an expression.
+func (r *Roller) Roll(expression *Expression) *Result {
+	result := &Result{Expression: expression, Requests: make([]RequestResult, len(expression.Requests))}
+	for i, request := range expression.Requests {
+		result.Requests[i] = r.RollRequest(request)
+		result.Total += result.Requests[i].Total
+	}
+	return result
+}
+
+// RollRequest rolls the dice of a single request.
+func (r *Roller) RollRequest(request Request) RequestResult {
+	result := RequestResult{Request: request}
+	if request.Type == SumModifier {
+		result.Total = request.Modifier
+		return result
+	}
+	result.Dice = make([]Die, request.Number)
+	for i := range result.Dice {
+		face := r.rollDie(request.Sides)
+		result.Dice[i] = Die{Face: face, Result: face + request.Modifier, Kept: true}
+		result.Total += result.Dice[i].Result
+	}
+	return result
+}
+
+func (r *Roller) rollDie(sides int) int {
+	if r.rand == nil {
+		return 1 + rand.Intn(sides) //nolint:gosec
+	}
+	return 1 + r.rand.Intn(sides)
+}
+
+// String formats the result of a request, such as '4d6+1: 3 5 2 7' or '+3'.
+func (r *RequestResult) String() string {
+	if r.Request.Type == SumModifier {
+		return fmt.Sprintf("%+d", r.Request.Modifier)
+	}
+	return fmt.Sprintf("%s: %s", r.Request.Code, r.diceResults())
+}
+
+// diceResults formats the results of the dice of a request, such as '3 5 2 7'.
+func (r *RequestResult) diceResults() string {
+	results := make([]string, len(r.Dice))
+	for i, die := range r.Dice {
+		results[i] = fmt.Sprint(die.Result)
+	}
+	return strings.Join(results, " ")
+}
+
+// HasDetails tells whether the details are needed to understand the total,
+// which is the case when more than one die was rolled.
+func (r *Result) HasDetails() bool {
+	dice := 0
+	for _, request := range r.Requests {
+		dice += len(request.Dice)
+	}
+	return dice > 1
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const rollActionAdvantage = "advantage"
+
+// attachRollActions adds the buttons to roll the query again to a dice post.
+func attachRollActions(post *model.Post) {
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{
+			rollAction("rollagain", "Roll again", false),
+			rollAction("rolladvantage", "Roll with advantage", true),
+		},
+	}})
+}
+
+func rollAction(id, name string, advantage bool) *model.PostAction {
+	return &model.PostAction{
+		Id:   id,
+		Name: name,
+		Type: model.PostActionTypeButton,
+		Integration: &model.PostActionIntegration{
+			URL: pluginURL("/api/v1/actions/roll"),
+			Context: map[string]any{
+				rollActionAdvantage: advantage,
+			},
+		},
+	}
+}
+
+// handleRollAction rolls again the query of a dice post for the user who clicked on one
+// of its buttons, and posts the result in the same thread. The query is read from the roll
+// stored in the post rather than from the request, which comes from the client.
+func (p *Plugin) handleRollAction(w http.ResponseWriter, r *http.Request) {
+	request, ok := readActionRequest(w, r)
+	if !ok {
+		return
+	}
+	// Both buttons are available to everyone, so the client choosing advantage is harmless
+	advantage, _ := request.Context[rollActionAdvantage].(bool)
+
+	post, appErr := p.API.GetPost(request.PostId)
+	if appErr != nil {
+		http.Error(w, appErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := getRollData(post)
+	if post.UserId != p.diceBotID || data == nil {
+		http.Error(w, "the post is not a dice roll", http.StatusBadRequest)
+		return
+	}
+	if !p.API.HasPermissionToChannel(request.UserId, post.ChannelId, model.PermissionCreatePost) {
+		writeActionResponse(w, "You cannot post in this channel.")
+		return
+	}
+
+	newPost, appErr := p.generateRerollPost(data, advantage, request.UserId, post.ChannelId, threadRootID(post))
+	if appErr != nil {
+		writeActionResponse(w, appErr.Message)
+		return
+	}
+	if _, appErr = p.createDicePost(newPost); appErr != nil {
+		http.Error(w, appErr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeActionResponse(w, "")
+}
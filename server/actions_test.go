@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollActionsAttached(t *testing.T) {
+	p, _ := initTestPlugin()
+	post, err := p.generateDicePost("2d6 +1", "userid", "channelid", "")
+	assert.Nil(t, err)
+	attachments := post.Attachments()
+	assert.Len(t, attachments, 1)
+	assert.Len(t, attachments[0].Actions, 2)
+	for i, advantage := range []bool{false, true} {
+		integration := attachments[0].Actions[i].Integration
+		assert.Equal(t, pluginURL("/api/v1/actions/roll"), integration.URL)
+		assert.Equal(t, advantage, integration.Context[rollActionAdvantage])
+	}
+}
+
+func rollActionRequest(advantage bool) *http.Request {
+	body, _ := json.Marshal(&model.PostActionIntegrationRequest{
+		PostId:  "postid",
+		Context: map[string]any{rollActionAdvantage: advantage},
+	})
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/actions/roll", strings.NewReader(string(body)))
+	r.Header.Set(headerUserID, "userid")
+	return r
+}
+
+// initTestRolledPost mocks a dice post of the query with the ID 'postid'.
+func initTestRolledPost(t *testing.T, p *Plugin, api *plugintest.API, query, rootID string) {
+	post, err := p.generateDicePost(query, "userid", "channelid", rootID)
+	assert.Nil(t, err)
+	post.Id = "postid"
+	api.On("GetPost", "postid").Return(post, nil)
+}
+
+func TestRollAction(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestRolledPost(t, p, api, "3d1 +1", "rootid")
+	api.On("HasPermissionToChannel", "userid", "channelid", model.PermissionCreatePost).Return(true)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, rollActionRequest(false))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, post)
+	assert.Equal(t, "channelid", post.ChannelId)
+	assert.Equal(t, "rootid", post.RootId)
+	assert.Equal(t, "**User** rolls `3d1 +1` = **4**\n- `3d1`: 1 1 1\n- +1", post.Message)
+
+	w = httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, rollActionRequest(true))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "**User** rolls `3d1 +1` with advantage = **4**\n- kept: **4** (`3d1`: 1 1 1, +1)\n- dropped: ~~4~~ (`3d1`: 1 1 1, +1)", post.Message)
+}
+
+func TestRollActionStartsThread(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestRolledPost(t, p, api, "d20", "")
+	api.On("HasPermissionToChannel", "userid", "channelid", model.PermissionCreatePost).Return(true)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, rollActionRequest(false))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "postid", post.RootId)
+}
+
+func TestRollActionWithoutPermission(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestRolledPost(t, p, api, "d20", "")
+	api.On("HasPermissionToChannel", "userid", "channelid", model.PermissionCreatePost).Return(false)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, rollActionRequest(false))
+	var response model.PostActionIntegrationResponse
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "You cannot post in this channel.", response.EphemeralText)
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+}
+
+func TestRollActionOnOtherPost(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	api.On("GetPost", "postid").Return(&model.Post{Id: "postid", UserId: "userid", ChannelId: "channelid"}, nil)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, rollActionRequest(false))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+}
+
+func TestRollActionOnMacroOfOtherUser(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestMacros(api, `{"fireball":"3d1 +2"}`, "", "")
+	initTestRolledPost(t, p, api, "fireball", "")
+	api.On("HasPermissionToChannel", "bobid", "channelid", model.PermissionCreatePost).Return(true)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	// The macros of Bob are not needed to roll the macro of the user again
+	r := rollActionRequest(false)
+	r.Header.Set(headerUserID, "bobid")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, post)
+	assert.Equal(t, "**User** rolls `3d1 +2` = **5**\n- `3d1`: 1 1 1\n- +2", post.Message)
+	data := getRollData(post)
+	assert.Equal(t, "3d1 +2", data.Query)
+	assert.Equal(t, "bobid", data.UserID)
+	api.AssertNotCalled(t, "KVGet", userMacrosKeyPrefix+"bobid")
+}
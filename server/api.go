@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+// rollRequest is the body of POST /api/v1/roll.
+type rollRequest struct {
+	Expression string `json:"expression"`
+	ChannelID  string `json:"channel_id"`
+	RootID     string `json:"root_id,omitempty"`
+	Label      string `json:"label,omitempty"`
+}
+
+// rollResponse is the response of POST /api/v1/roll.
+type rollResponse struct {
+	PostID string    `json:"post_id"`
+	Roll   *rollData `json:"roll"`
+}
+
+// handleRoll rolls an expression on behalf of the authenticated user, posts it in
+// a channel as the dice bot, and returns the structured roll.
+func (p *Plugin) handleRoll(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(headerUserID)
+	if userID == "" {
+		writeError(w, http.StatusUnauthorized, "Not authorized.")
+		return
+	}
+	var request rollRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	request.Expression = strings.TrimSpace(request.Expression)
+	request.Label = strings.TrimSpace(request.Label)
+	if request.Expression == "" || request.ChannelID == "" {
+		writeError(w, http.StatusBadRequest, "The expression and the channel_id are required.")
+		return
+	}
+	if request.Label != "" && !plainTextRegexp.MatchString(request.Label) {
+		writeError(w, http.StatusBadRequest, "The label may only contain "+plainTextRule+".")
+		return
+	}
+	if !p.API.HasPermissionToChannel(userID, request.ChannelID, model.PermissionCreatePost) {
+		writeError(w, http.StatusForbidden, "You cannot post in this channel.")
+		return
+	}
+
+	post, appErr := p.generateDicePost(request.Expression, userID, request.ChannelID, request.RootID)
+	if appErr != nil {
+		writeError(w, appErr.StatusCode, appErr.Message)
+		return
+	}
+	if request.Label != "" {
+		setRollLabel(post, request.Label)
+	}
+	createdPost, appErr := p.createDicePost(post)
+	if appErr != nil {
+		writeError(w, http.StatusInternalServerError, appErr.Message)
+		return
+	}
+
+	writeJSON(w, &rollResponse{PostID: createdPost.Id, Roll: getRollData(post)})
+}
+
+// evaluateRequest is the body of the inter-plugin request POST /roll/evaluate.
+type evaluateRequest struct {
+	Expression string `json:"expression"`
+}
+
+// evaluateResponse is the response of POST /roll/evaluate. Its version is the version of
+// the structured roll format.
+type evaluateResponse struct {
+	Version    int                `json:"version"`
+	Expression string             `json:"expression"`
+	Total      int                `json:"total"`
+	Requests   []dice.RequestData `json:"requests"`
+}
+
+// handleEvaluate rolls an expression for another plugin, without posting it.
+func (p *Plugin) handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(headerPluginID) == "" {
+		writeError(w, http.StatusUnauthorized, "Only other plugins can evaluate expressions.")
+		return
+	}
+	var request evaluateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+	expression := strings.TrimSpace(request.Expression)
+
+	result, appErr := rollQuery(expression)
+	if appErr != nil {
+		writeError(w, appErr.StatusCode, appErr.Message)
+		return
+	}
+
+	writeJSON(w, &evaluateResponse{
+		Version:    rollDataVersion,
+		Expression: expression,
+		Total:      result.Total,
+		Requests:   result.Data(),
+	})
+}
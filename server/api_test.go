@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func apiRollRequest(userID, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/roll", strings.NewReader(body))
+	if userID != "" {
+		r.Header.Set(headerUserID, userID)
+	}
+	return r
+}
+
+func TestAPIRoll(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	p.diceBotID = "botid"
+	api.On("HasPermissionToChannel", "userid", "channelid", model.PermissionCreatePost).Return(true)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		created.Id = "postid"
+		return created, nil
+	})
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, apiRollRequest("userid", `{"expression":"2d1 +3","channel_id":"channelid","label":"Stealth (Dex +3)"}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "botid", post.UserId)
+	assert.Equal(t, "**Stealth (Dex +3)**\n**User** rolls `2d1 +3` = **5**\n- `2d1`: 1 1\n- +3", post.Message)
+
+	var response rollResponse
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "postid", response.PostID)
+	assert.Equal(t, 5, response.Roll.Total)
+	assert.Equal(t, "Stealth (Dex +3)", response.Roll.Label)
+	assert.Equal(t, "userid", response.Roll.UserID)
+	assert.Len(t, response.Roll.Requests, 2)
+}
+
+func TestAPIRollErrors(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	api.On("HasPermissionToChannel", "userid", "channelid", model.PermissionCreatePost).Return(true)
+	api.On("HasPermissionToChannel", "userid", "otherchannelid", model.PermissionCreatePost).Return(false)
+
+	testCases := []struct {
+		userID     string
+		body       string
+		statusCode int
+	}{
+		{userID: "", body: `{"expression":"d20","channel_id":"channelid"}`, statusCode: http.StatusUnauthorized},
+		{userID: "userid", body: `not json`, statusCode: http.StatusBadRequest},
+		{userID: "userid", body: `{"channel_id":"channelid"}`, statusCode: http.StatusBadRequest},
+		{userID: "userid", body: `{"expression":"6d","channel_id":"channelid"}`, statusCode: http.StatusBadRequest},
+		{userID: "userid", body: `{"expression":"d20","channel_id":"otherchannelid"}`, statusCode: http.StatusForbidden},
+		{userID: "userid", body: `{"expression":"d20","channel_id":"channelid","label":"@channel"}`, statusCode: http.StatusBadRequest},
+		{userID: "userid", body: `{"expression":"d20","channel_id":"channelid","label":"Stealth @here"}`, statusCode: http.StatusBadRequest},
+		{userID: "userid", body: `{"expression":"d20","channel_id":"channelid","label":"[Stealth](https://example.com)"}`, statusCode: http.StatusBadRequest},
+	}
+	for _, testCase := range testCases {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(&plugin.Context{}, w, apiRollRequest(testCase.userID, testCase.body))
+		assert.Equal(t, testCase.statusCode, w.Code, testCase.body)
+		var response apiError
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&response), testCase.body)
+		assert.NotEmpty(t, response.Error, testCase.body)
+	}
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+}
+
+func TestEvaluate(t *testing.T) {
+	p, _ := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+
+	r := httptest.NewRequest(http.MethodPost, "/roll/evaluate", strings.NewReader(`{"expression":" 2d1+1 -2 "}`))
+	r.Header.Set(headerPluginID, "otherplugin")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"version":1,"expression":"2d1+1 -2","total":2,"requests":[`+
+		`{"code":"2d1+1","type":"numeric","sides":1,"modifier":1,"total":4,"dice":[{"face":1,"result":2,"kept":true},{"face":1,"result":2,"kept":true}]},`+
+		`{"code":"-2","type":"sumModifier","modifier":-2,"total":-2}]}`, w.Body.String())
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	p, _ := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+
+	testCases := []struct {
+		pluginID   string
+		body       string
+		statusCode int
+	}{
+		{pluginID: "", body: `{"expression":"d20"}`, statusCode: http.StatusUnauthorized},
+		{pluginID: "otherplugin", body: `not json`, statusCode: http.StatusBadRequest},
+		{pluginID: "otherplugin", body: `{"expression":""}`, statusCode: http.StatusBadRequest},
+		{pluginID: "otherplugin", body: `{"expression":"6d"}`, statusCode: http.StatusBadRequest},
+	}
+	for _, testCase := range testCases {
+		r := httptest.NewRequest(http.MethodPost, "/roll/evaluate", strings.NewReader(testCase.body))
+		if testCase.pluginID != "" {
+			r.Header.Set(headerPluginID, testCase.pluginID)
+		}
+		w := httptest.NewRecorder()
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		assert.Equal(t, testCase.statusCode, w.Code, testCase.body)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+const (
+	// attackMacroName is both the attack subcommand and a common macro name: the macro wins.
+	attackMacroName string = "attack"
+	// criticalHitFace and criticalMissFace are the natural rolls of the d20 that always hit or miss.
+	criticalHitFace  int = 20
+	criticalMissFace int = 1
+)
+
+// attackQuery is a parsed '/roll attack <bonus> <damage> [ac <armor class>]' query.
+type attackQuery struct {
+	toHit  string
+	damage string
+	ac     int
+	hasAC  bool
+}
+
+// isAttackQuery tells whether the query of the attack subcommand looks like an attack roll,
+// such as '+7 2d6 +4', rather than a roll of a macro named 'attack', such as '1d4'.
+func isAttackQuery(query string) bool {
+	fields := strings.Fields(query)
+	return len(fields) >= 2 && strings.ContainsAny(fields[0][:1], "+-")
+}
+
+func parseAttackQuery(query string) (*attackQuery, *model.AppError) {
+	fields := strings.Fields(query)
+	attack := &attackQuery{}
+	if len(fields) >= 2 && strings.EqualFold(fields[len(fields)-2], "ac") {
+		ac, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil || ac < 0 {
+			return nil, appError(fmt.Sprintf("'%s' is not a valid armor class, use a number such as `ac 15`.", fields[len(fields)-1]), err)
+		}
+		attack.ac, attack.hasAC = ac, true
+		fields = fields[:len(fields)-2]
+	}
+	if len(fields) < 2 {
+		return nil, appError("Use `/roll attack <bonus> <damage> [ac <armor class>]`, for example `/roll attack +7 2d6 +4 ac 15`.", nil)
+	}
+	attack.toHit = "1d20" + fields[0]
+	attack.damage = strings.Join(fields[1:], " ")
+	return attack, nil
+}
+
+// expand returns the attack query with the macros of the damage expanded, such as '+7 2d6 +4 ac 15'.
+func (a *attackQuery) expand(damage *dice.Expression) string {
+	query := strings.TrimPrefix(a.toHit, "1d20") + " " + damage.Text
+	if a.hasAC {
+		query += fmt.Sprintf(" ac %d", a.ac)
+	}
+	return query
+}
+
+// attackOutcome tells whether an attack hits, and whether it is a critical hit: a natural 20
+// always hits and a natural 1 always misses. Without an armor class, every other roll hits.
+func attackOutcome(natural, total int, attack *attackQuery) (hit bool, critical bool) {
+	switch natural {
+	case criticalHitFace:
+		return true, true
+	case criticalMissFace:
+		return false, false
+	}
+	return !attack.hasAC || total >= attack.ac, false
+}
+
+// criticalDamage returns the damage expression of a critical hit, rolling every die twice:
+// the extra dice are rolled without their modifiers.
+func criticalDamage(expression *dice.Expression) *dice.Expression {
+	critical := &dice.Expression{Text: expression.Text, Requests: slices.Clone(expression.Requests)}
+	for _, request := range expression.Requests {
+		if request.Type != dice.Numeric {
+			continue
+		}
+		extra := dice.Request{
+			Code:   fmt.Sprintf("%dd%d", request.Number, request.Sides),
+			Type:   dice.Numeric,
+			Number: request.Number,
+			Sides:  request.Sides,
+		}
+		critical.Requests = append(critical.Requests, extra)
+		critical.Text += " " + extra.Code
+	}
+	return critical
+}
+
+// isAttackCommand tells whether a '/roll attack ...' command is an attack roll, unless the user
+// has a macro named 'attack'.
+func (p *Plugin) isAttackCommand(args *model.CommandArgs, query string) (bool, *model.AppError) {
+	if !isAttackQuery(query) {
+		return false, nil
+	}
+	hasMacro, appErr := p.hasMacro(args.UserId, args.ChannelId, attackMacroName)
+	return !hasMacro, appErr
+}
+
+// executeAttackCommand handles '/roll attack <bonus> <damage> [ac <armor class>]'.
+func (p *Plugin) executeAttackCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	post, appErr := p.generateAttackPost(query, args.UserId, args.ChannelId, args.RootId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if _, appErr := p.createDicePost(post); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{}, nil
+}
+
+// generateAttackPost rolls to hit, then rolls the damage if the attack hits, in a single post.
+// The query is the query of the attack subcommand, such as '+7 2d6 +4 ac 15'.
+func (p *Plugin) generateAttackPost(query, userID, channelID, rootID string) (*model.Post, *model.AppError) {
+	attack, appErr := parseAttackQuery(query)
+	if appErr != nil {
+		return nil, appErr
+	}
+	displayName, appErr := p.getDisplayName(userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	toHitExpression, appErr := p.parseUserExpression(userID, channelID, attack.toHit)
+	if appErr != nil {
+		return nil, appErr
+	}
+	damageExpression, appErr := p.parseUserExpression(userID, channelID, attack.damage)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	expanded := attack.expand(damageExpression)
+	toHit := roller.Roll(toHitExpression)
+	hit, critical := attackOutcome(toHit.Requests[0].Dice[0].Face, toHit.Total, attack)
+	outcome := &attackData{AC: attack.ac, Hit: hit, Critical: critical}
+
+	text := toHit.Breakdown(displayName)
+	switch {
+	case critical:
+		text += "\n**Critical hit!** The damage dice are doubled."
+	case toHit.Requests[0].Dice[0].Face == criticalMissFace:
+		text += "\n**Critical miss!**"
+	case attack.hasAC && hit:
+		text += "\n**Hit!**"
+	case attack.hasAC:
+		text += "\n**Miss!**"
+	}
+	if hit {
+		if critical {
+			damageExpression = criticalDamage(damageExpression)
+		}
+		damage := roller.Roll(damageExpression)
+		text += "\n" + damage.Breakdown(displayName)
+		outcome.DamageTotal = damage.Total
+		outcome.Damage = damage.Data()
+	}
+
+	post := &model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: channelID,
+		RootId:    rootID,
+		Message:   text,
+	}
+	setRollData(post, &rollData{
+		Version:    rollDataVersion,
+		Query:      attackMacroName + " " + query,
+		Expression: attackMacroName + " " + expanded,
+		UserID:     userID,
+		Total:      toHit.Total,
+		Requests:   toHit.Data(),
+		Attack:     outcome,
+	})
+	label := "Attack"
+	if attack.hasAC {
+		label = fmt.Sprintf("Attack against AC %d", attack.ac)
+	}
+	setRollLabel(post, label)
+	return post, nil
+}
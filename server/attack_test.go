@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+// seedRoller makes the rolls of a test reproducible.
+func seedRoller(t *testing.T, seed int64) {
+	previous := roller
+	roller = *dice.NewRoller(rand.NewSource(seed))
+	t.Cleanup(func() { roller = previous })
+}
+
+func TestIsAttackQuery(t *testing.T) {
+	for _, query := range []string{"+7 2d6+4", "-1 d4 ac 12", "+@str+@prof longsword"} {
+		assert.True(t, isAttackQuery(query), query)
+	}
+	for _, query := range []string{"", "+2", "1d4", "d20 +7", "2 +1"} {
+		assert.False(t, isAttackQuery(query), query)
+	}
+}
+
+func TestParseAttackQuery(t *testing.T) {
+	attack, err := parseAttackQuery("+7 2d6+4 d4 AC 15")
+	assert.Nil(t, err)
+	assert.Equal(t, &attackQuery{toHit: "1d20+7", damage: "2d6+4 d4", ac: 15, hasAC: true}, attack)
+
+	attack, err = parseAttackQuery("-1 d4")
+	assert.Nil(t, err)
+	assert.Equal(t, &attackQuery{toHit: "1d20-1", damage: "d4"}, attack)
+
+	for _, query := range []string{"+7 ac 15", "+7 2d6 ac high", "+7 2d6 ac -2"} {
+		_, err := parseAttackQuery(query)
+		assert.NotNil(t, err, query)
+	}
+}
+
+func TestAttackOutcome(t *testing.T) {
+	withAC := &attackQuery{ac: 15, hasAC: true}
+	for _, testCase := range []struct {
+		natural, total int
+		attack         *attackQuery
+		hit, critical  bool
+	}{
+		{natural: 20, total: 19, attack: withAC, hit: true, critical: true},
+		{natural: 1, total: 30, attack: withAC, hit: false},
+		{natural: 8, total: 15, attack: withAC, hit: true},
+		{natural: 7, total: 14, attack: withAC, hit: false},
+		{natural: 2, total: 2, attack: &attackQuery{}, hit: true},
+		{natural: 1, total: 8, attack: &attackQuery{}, hit: false},
+	} {
+		hit, critical := attackOutcome(testCase.natural, testCase.total, testCase.attack)
+		assert.Equal(t, testCase.hit, hit, testCase)
+		assert.Equal(t, testCase.critical, critical, testCase)
+	}
+}
+
+func TestCriticalDamage(t *testing.T) {
+	expression, _ := dice.Parse("2d6+4 +1 d8")
+	critical := criticalDamage(expression)
+	assert.Equal(t, "2d6+4 +1 d8 2d6 1d8", critical.Text)
+	assert.Len(t, critical.Requests, 5)
+	assert.Equal(t, dice.Request{Code: "2d6", Type: dice.Numeric, Number: 2, Sides: 6}, critical.Requests[3])
+	assert.Equal(t, "2d6+4 +1 d8", expression.Text)
+	assert.Len(t, expression.Requests, 3)
+}
+
+func TestAttackCommand(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestMacros(api, "", "", "")
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+	args := &model.CommandArgs{UserId: "userid", ChannelId: "channelid"}
+
+	for _, testCase := range []struct {
+		seed     int64
+		command  string
+		expected string
+	}{
+		{
+			seed:     3,
+			command:  "/roll attack +7 2d1+1 ac 16",
+			expected: "**Attack against AC 16**\n**User** rolls `1d20+7` = **16**\n**Hit!**\n**User** rolls `2d1+1` = **4**\n- `2d1+1`: 2 2",
+		},
+		{
+			// The modifier of a die code is added to each die, a separate modifier once
+			seed:     3,
+			command:  "/roll attack +7 2d1 +1 ac 16",
+			expected: "**Attack against AC 16**\n**User** rolls `1d20+7` = **16**\n**Hit!**\n**User** rolls `2d1 +1` = **3**\n- `2d1`: 1 1\n- +1",
+		},
+		{
+			seed:     103,
+			command:  "/roll attack +7 2d1 +1",
+			expected: "**Attack**\n**User** rolls `1d20+7` = **27**\n**Critical hit!** The damage dice are doubled.\n**User** rolls `2d1 +1 2d1` = **5**\n- `2d1`: 1 1\n- +1\n- `2d1`: 1 1",
+		},
+		{
+			seed:     3,
+			command:  "/roll attack +6 2d1+1 ac 16",
+			expected: "**Attack against AC 16**\n**User** rolls `1d20+6` = **15**\n**Miss!**",
+		},
+		{
+			seed:     11,
+			command:  "/roll attack +7 2d1+1",
+			expected: "**Attack**\n**User** rolls `1d20+7` = **8**\n**Critical miss!**",
+		},
+		{
+			seed:     103,
+			command:  "/roll attack +7 2d1+1 ac 30",
+			expected: "**Attack against AC 30**\n**User** rolls `1d20+7` = **27**\n**Critical hit!** The damage dice are doubled.\n**User** rolls `2d1+1 2d1` = **6**\n- `2d1+1`: 2 2\n- `2d1`: 1 1",
+		},
+	} {
+		seedRoller(t, testCase.seed)
+		args.Command = testCase.command
+		response, err := p.ExecuteCommand(&plugin.Context{}, args)
+		assert.Nil(t, err, testCase.command)
+		assert.NotNil(t, response, testCase.command)
+		assert.Equal(t, testCase.expected, post.Message, testCase.command)
+	}
+
+	data := getRollData(post)
+	assert.Equal(t, "attack +7 2d1+1 ac 30", data.Query)
+	assert.Equal(t, 27, data.Total)
+	assert.Equal(t, &attackData{AC: 30, Hit: true, Critical: true, DamageTotal: 6, Damage: data.Attack.Damage}, data.Attack)
+	assert.Len(t, data.Attack.Damage, 2)
+}
+
+func TestAttackMacro(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestMacros(api, `{"attack":"3d1"}`, "", "")
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll attack +2 1d1", UserId: "userid", ChannelId: "channelid"})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, "**User** rolls `3d1 +2 1d1` = **6**\n- `3d1`: 1 1 1\n- +2\n- `1d1`: 1", post.Message)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// blindRollKeyPrefix prefixes the KV store keys of the blind rolls, followed by the placeholder post ID.
+const blindRollKeyPrefix = "blind_"
+
+// blindRoll is a roll hidden to the channel until a game master reveals it.
+type blindRoll struct {
+	UserID  string
+	Message string
+}
+
+// executeBlindRollCommand sends the roll to the game masters only, and posts a placeholder
+// in the channel that the game masters can use to reveal the roll.
+func (p *Plugin) executeBlindRollCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	gmIDs, appErr := p.getChannelGMs(args.ChannelId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(gmIDs) == 0 {
+		return nil, appError("There is no game master in this channel to send the blind roll to.", nil)
+	}
+
+	post, appErr := p.generateDicePost(query, args.UserId, args.ChannelId, args.RootId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	displayName, appErr := p.getDisplayName(args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	// The game masters get the roll first, so that nothing is left in the channel when none can
+	failedIDs, appErr := p.sendToGMs(gmIDs, args.ChannelId, "Blind roll", post.Message)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	placeholder := &model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   fmt.Sprintf("**%s** made a blind roll.", displayName),
+	}
+	model.ParseSlackAttachment(placeholder, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{{
+			Id:   "reveal",
+			Name: "Reveal",
+			Type: model.PostActionTypeButton,
+			Integration: &model.PostActionIntegration{
+				URL: pluginURL("/api/v1/blind/reveal"),
+			},
+		}},
+	}})
+	placeholder, appErr = p.API.CreatePost(placeholder)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if appErr = kvSet(p, blindRollKeyPrefix+placeholder.Id, &blindRoll{UserID: args.UserId, Message: post.Message}); appErr != nil {
+		return nil, appErr
+	}
+	p.warnFailedGMs(args, failedIDs)
+	return &model.CommandResponse{}, nil
+}
+
+// handleRevealBlindRoll replaces the placeholder of a blind roll by its result, when
+// a game master clicks on the Reveal button.
+func (p *Plugin) handleRevealBlindRoll(w http.ResponseWriter, r *http.Request) {
+	request, ok := readActionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	post, appErr := p.API.GetPost(request.PostId)
+	if appErr != nil {
+		http.Error(w, appErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The channel of the request comes from the client: check the game masters of the post's channel
+	canReveal, appErr := p.canManageGame(post.ChannelId, request.UserId)
+	if appErr != nil {
+		http.Error(w, appErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !canReveal {
+		writeActionResponse(w, "Only the game masters of this channel can reveal a blind roll.")
+		return
+	}
+
+	// Taking the roll atomically ensures that it is only revealed once
+	roll, appErr := kvTake[blindRoll](p, blindRollKeyPrefix+request.PostId)
+	if appErr != nil {
+		http.Error(w, appErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	if roll == nil {
+		writeActionResponse(w, "This blind roll was already revealed.")
+		return
+	}
+
+	post.Message = roll.Message
+	post.DelProp(model.PostPropsAttachments)
+	if _, appErr = p.API.UpdatePost(post); appErr != nil {
+		// Keep the roll so that it can be revealed again
+		if restoreErr := kvSet(p, blindRollKeyPrefix+request.PostId, roll); restoreErr != nil {
+			p.API.LogError("Failed to restore a blind roll", "post_id", request.PostId, "error", restoreErr.Error())
+		}
+		http.Error(w, appErr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeActionResponse(w, "")
+}
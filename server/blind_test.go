@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// initTestGM makes gmid the only game master of channelid, as its channel admin.
+func initTestGM(api *plugintest.API) {
+	api.On("KVGet", gmsKeyPrefix+"channelid").Return(nil, nil)
+	api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false)
+	api.On("GetChannelMember", "channelid", "gmid").Return(&model.ChannelMember{UserId: "gmid", SchemeAdmin: true}, nil)
+	api.On("GetChannelMember", "channelid", "userid").Return(&model.ChannelMember{UserId: "userid"}, nil)
+	api.On("GetChannelMembers", "channelid", 0, channelMembersPerPage).Return(model.ChannelMembers{
+		{UserId: "gmid", SchemeAdmin: true},
+		{UserId: "userid"},
+	}, nil)
+}
+
+func TestBlindRoll(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	initTestGM(api)
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", DisplayName: "Campaign"}, nil)
+	api.On("GetDirectChannel", "botid", "gmid").Return(&model.Channel{Id: "dmid"}, nil)
+	posts := map[string]*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) (*model.Post, *model.AppError) {
+		posts[post.ChannelId] = post
+		post.Id = "postid"
+		return post, nil
+	})
+	api.On("KVSet", blindRollKeyPrefix+"postid", mock.Anything).Return(nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll blind 3d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, "**User** made a blind roll.", posts["channelid"].Message)
+	assert.Len(t, posts["channelid"].Attachments(), 1)
+	assert.Equal(t, "Blind roll in **Campaign**:\n**User** rolls `3d1` = **3**\n- `3d1`: 1 1 1", posts["dmid"].Message)
+	api.AssertCalled(t, "KVSet", blindRollKeyPrefix+"postid", []byte("{\"UserID\":\"userid\",\"Message\":\"**User** rolls `3d1` = **3**\\n- `3d1`: 1 1 1\"}"))
+}
+
+func TestBlindRollWithoutReachableGM(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	initTestGM(api)
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", DisplayName: "Campaign"}, nil)
+	api.On("GetDirectChannel", "botid", "gmid").Return(nil, &model.AppError{Message: "no DM"})
+	api.On("LogError", "Failed to send a roll to a game master", "user_id", "gmid", "channel_id", "channelid", "error", mock.Anything).Return()
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll blind 3d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+}
+
+func revealRequest(userID, channelID string) *http.Request {
+	body, _ := json.Marshal(&model.PostActionIntegrationRequest{ChannelId: channelID, PostId: "postid"})
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/blind/reveal", strings.NewReader(string(body)))
+	r.Header.Set(headerUserID, userID)
+	return r
+}
+
+func TestRevealBlindRoll(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestGM(api)
+	roll := []byte("{\"UserID\":\"userid\",\"Message\":\"**User** rolls `d20` = **12**\"}")
+	api.On("KVGet", blindRollKeyPrefix+"postid").Return(roll, nil).Once()
+	api.On("KVGet", blindRollKeyPrefix+"postid").Return(nil, nil)
+	api.On("KVCompareAndDelete", blindRollKeyPrefix+"postid", roll).Return(true, nil)
+	placeholder := &model.Post{Id: "postid", ChannelId: "channelid", Message: "**User** made a blind roll."}
+	model.ParseSlackAttachment(placeholder, []*model.SlackAttachment{{}})
+	api.On("GetPost", "postid").Return(placeholder, nil)
+	var updated *model.Post
+	api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(*model.Post)
+	})
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, revealRequest("userid", "channelid"))
+	var response model.PostActionIntegrationResponse
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.NotEmpty(t, response.EphemeralText)
+	assert.Nil(t, updated)
+
+	w = httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, revealRequest("gmid", "channelid"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, updated)
+	assert.Equal(t, "**User** rolls `d20` = **12**", updated.Message)
+	assert.Nil(t, updated.GetProp(model.PostPropsAttachments))
+
+	// Another game master clicking at the same time does not reveal it again
+	w = httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, revealRequest("gmid", "channelid"))
+	response = model.PostActionIntegrationResponse{}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "This blind roll was already revealed.", response.EphemeralText)
+	api.AssertNumberOfCalls(t, "UpdatePost", 1)
+}
+
+func TestRevealBlindRollUnauthenticated(t *testing.T) {
+	p, _ := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, revealRequest("", "channelid"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRevealBlindRollFromOtherChannel(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestGM(api)
+	// userid administers their own channel, but not the channel of the blind roll
+	api.On("KVGet", gmsKeyPrefix+"ownchannelid").Return(nil, nil)
+	api.On("GetChannelMember", "ownchannelid", "userid").Return(&model.ChannelMember{UserId: "userid", SchemeAdmin: true}, nil)
+	api.On("GetPost", "postid").Return(&model.Post{Id: "postid", ChannelId: "channelid"}, nil)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(&plugin.Context{}, w, revealRequest("userid", "ownchannelid"))
+	var response model.PostActionIntegrationResponse
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "Only the game masters of this channel can reveal a blind roll.", response.EphemeralText)
+	api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+	api.AssertNotCalled(t, "KVCompareAndDelete", mock.Anything, mock.Anything)
+}
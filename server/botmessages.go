@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+// botMentionRegexp matches the mentions of the dice bot in a message.
+var botMentionRegexp = regexp.MustCompile(`(?i)(^|\s)@` + botUsername + `\b`)
+
+// MessageHasBeenPosted rolls the messages mentioning the dice bot, or sent to it in a direct
+// message, such as '@dicerollerbot 1d20+5'. The bot answers in the thread of the message.
+func (p *Plugin) MessageHasBeenPosted(_ *plugin.Context, post *model.Post) {
+	if post.UserId == p.diceBotID || post.IsSystemMessage() ||
+		post.GetProp(model.PostPropsFromBot) == "true" || post.GetProp(model.PostPropsFromWebhook) == "true" {
+		return
+	}
+	if !botMentionRegexp.MatchString(post.Message) {
+		isDirect, appErr := p.isDirectChannelWithBot(post.ChannelId)
+		if appErr != nil {
+			p.API.LogWarn("Failed to read the channel of a message", "channel_id", post.ChannelId, "error", appErr.Error())
+			return
+		}
+		if !isDirect {
+			return
+		}
+	}
+
+	rootID := post.RootId
+	if rootID == "" {
+		rootID = post.Id
+	}
+	query := readBotMessageQuery(post.Message)
+	if query == "" {
+		p.sendBotReply(post.UserId, post.ChannelId, rootID, "Send me a dice expression to roll, such as `1d20+5`.")
+		return
+	}
+
+	dicePost, appErr := p.generateDicePost(query, post.UserId, post.ChannelId, rootID)
+	if appErr == nil {
+		_, appErr = p.createDicePost(dicePost)
+	}
+	var parseErr *dice.ParseError
+	switch {
+	case appErr != nil && errors.As(appErr, &parseErr):
+		p.sendBotReply(post.UserId, post.ChannelId, rootID, parseErrorResponse(query, parseErr).Text)
+	case appErr != nil:
+		p.sendBotReply(post.UserId, post.ChannelId, rootID, appErr.Message)
+	}
+}
+
+// readBotMessageQuery reads the roll expression of a message sent to the dice bot, without the
+// mentions of the bot and the optional '/roll' or 'roll' command.
+func readBotMessageQuery(message string) string {
+	query := strings.Join(strings.Fields(botMentionRegexp.ReplaceAllString(message, " ")), " ")
+	for _, command := range []string{"/" + trigger, trigger} {
+		if subcommand, subquery := splitSubcommand(query); strings.EqualFold(subcommand, command) {
+			return subquery
+		}
+	}
+	return query
+}
+
+// isDirectChannelWithBot tells whether a channel is a direct message channel with the dice bot.
+// The channel is only read once, for the first message posted in it.
+func (p *Plugin) isDirectChannelWithBot(channelID string) (bool, *model.AppError) {
+	if isDirect, ok := p.botDirectChannels.Load(channelID); ok {
+		return isDirect.(bool), nil
+	}
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return false, appErr
+	}
+	isDirect := channel.Type == model.ChannelTypeDirect && slices.Contains(strings.Split(channel.Name, "__"), p.diceBotID)
+	p.botDirectChannels.Store(channelID, isDirect)
+	return isDirect, nil
+}
+
+// sendBotReply answers a user in a thread with a message only visible to them.
+func (p *Plugin) sendBotReply(userID, channelID, rootID, message string) {
+	p.API.SendEphemeralPost(userID, &model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: channelID,
+		RootId:    rootID,
+		Message:   message,
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBotMessageQuery(t *testing.T) {
+	for message, expected := range map[string]string{
+		"@dicerollerbot 1d20+5":        "1d20+5",
+		"2d6 +1 @DiceRollerBot":        "2d6 +1",
+		"@dicerollerbot /roll 4d6":     "4d6",
+		"Roll 1d8":                     "1d8",
+		"@dicerollerbot":               "",
+		"@dicerollerbot2 1d4":          "@dicerollerbot2 1d4",
+		"d20 @dicerollerbot @someone ": "d20 @someone",
+	} {
+		assert.Equal(t, expected, readBotMessageQuery(message), message)
+	}
+}
+
+func TestMessageMentioningBot(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	p.diceBotID = "botid"
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", Type: model.ChannelTypeOpen}, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+	var reply *model.Post
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		reply = args.Get(1).(*model.Post)
+	})
+
+	p.MessageHasBeenPosted(&plugin.Context{}, &model.Post{Id: "postid", UserId: "userid", ChannelId: "channelid", Message: "@dicerollerbot 3d1"})
+	assert.NotNil(t, post)
+	assert.Equal(t, "botid", post.UserId)
+	assert.Equal(t, "postid", post.RootId)
+	assert.Equal(t, "**User** rolls `3d1` = **3**\n- `3d1`: 1 1 1", post.Message)
+
+	p.MessageHasBeenPosted(&plugin.Context{}, &model.Post{Id: "replyid", RootId: "rootid", UserId: "userid", ChannelId: "channelid", Message: "@dicerollerbot 2x6"})
+	assert.Equal(t, "rootid", reply.RootId)
+	assert.Contains(t, reply.Message, "Did you mean `/roll 2d6`?")
+
+	post = nil
+	for _, ignored := range []*model.Post{
+		{UserId: "userid", ChannelId: "channelid", Message: "1d20"},
+		{UserId: "botid", ChannelId: "channelid", Message: "@dicerollerbot 1d20"},
+		{UserId: "userid", ChannelId: "channelid", Message: "@dicerollerbot 1d20", Type: model.PostTypeJoinChannel},
+		{UserId: "otherbotid", ChannelId: "channelid", Message: "@dicerollerbot 1d20", Props: model.StringInterface{model.PostPropsFromBot: "true"}},
+	} {
+		p.MessageHasBeenPosted(&plugin.Context{}, ignored)
+		assert.Nil(t, post, ignored.Message)
+	}
+}
+
+func TestDirectMessageToBot(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	p.diceBotID = "botid"
+	api.On("GetChannel", "dmid").Return(&model.Channel{Id: "dmid", Type: model.ChannelTypeDirect, Name: "botid__userid"}, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+	var reply *model.Post
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		reply = args.Get(1).(*model.Post)
+	})
+
+	p.MessageHasBeenPosted(&plugin.Context{}, &model.Post{Id: "postid", UserId: "userid", ChannelId: "dmid", Message: "roll 2d1"})
+	assert.Equal(t, "dmid", post.ChannelId)
+	assert.Equal(t, "**User** rolls `2d1` = **2**\n- `2d1`: 1 1", post.Message)
+
+	p.MessageHasBeenPosted(&plugin.Context{}, &model.Post{Id: "postid", UserId: "userid", ChannelId: "dmid", Message: "  "})
+	assert.Equal(t, "Send me a dice expression to roll, such as `1d20+5`.", reply.Message)
+}
+
+func TestDirectChannelWithBotCached(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", Type: model.ChannelTypeOpen}, nil)
+
+	for range 3 {
+		p.MessageHasBeenPosted(&plugin.Context{}, &model.Post{Id: "postid", UserId: "userid", ChannelId: "channelid", Message: "Hello"})
+	}
+	api.AssertNumberOfCalls(t, "GetChannel", 1)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// charactersKeyPrefix prefixes the KV store keys of the characters of a user, followed by
+	// the user ID. activeCharacterKeyPrefix prefixes the keys of the active character of a user
+	// in a channel, followed by the channel ID and the user ID.
+	charactersKeyPrefix      string = "characters_"
+	activeCharacterKeyPrefix string = "activecharacter_"
+	maxCharacters            int    = 50
+	maxCharacterNameLength   int    = 64
+)
+
+func charactersKey(userID string) string {
+	return charactersKeyPrefix + userID
+}
+
+func activeCharacterKey(userID, channelID string) string {
+	return activeCharacterKeyPrefix + channelID + "_" + userID
+}
+
+// characterKey identifies a character by its name, ignoring case and repeated spaces.
+func characterKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func (p *Plugin) getCharacters(userID string) (map[string]characterSheet, *model.AppError) {
+	characters, appErr := kvGet[map[string]characterSheet](p, charactersKey(userID))
+	if appErr != nil || characters == nil {
+		return map[string]characterSheet{}, appErr
+	}
+	return *characters, nil
+}
+
+// saveCharacter adds or replaces a character of a user, and makes it the active character in the channel.
+func (p *Plugin) saveCharacter(userID, channelID string, sheet *characterSheet) *model.AppError {
+	if len(sheet.Name) > maxCharacterNameLength {
+		return appError(fmt.Sprintf("Character names must be up to %d characters long.", maxCharacterNameLength), nil)
+	}
+	key := characterKey(sheet.Name)
+	if _, appErr := kvUpdate(p, charactersKey(userID), func(characters *map[string]characterSheet) *model.AppError {
+		if *characters == nil {
+			*characters = map[string]characterSheet{}
+		}
+		if _, ok := (*characters)[key]; !ok && len(*characters) >= maxCharacters {
+			return appError(fmt.Sprintf("You cannot have more than %d characters. Delete one with `/roll character delete <name>`.", maxCharacters), nil)
+		}
+		(*characters)[key] = *sheet
+		return nil
+	}); appErr != nil {
+		return appErr
+	}
+	return kvSet(p, activeCharacterKey(userID, channelID), key)
+}
+
+// getActiveCharacter returns the character a user plays in a channel: the one chosen with
+// '/roll character use', or the only character of the user.
+func (p *Plugin) getActiveCharacter(userID, channelID string) (*characterSheet, *model.AppError) {
+	characters, appErr := p.getCharacters(userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(characters) == 0 {
+		return nil, appError("You have no character sheet. Import one with `/roll sheet import`.", nil)
+	}
+	active, appErr := kvGet[string](p, activeCharacterKey(userID, channelID))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if active != nil {
+		if sheet, ok := characters[*active]; ok {
+			return &sheet, nil
+		}
+	}
+	if len(characters) == 1 {
+		for _, sheet := range characters {
+			return &sheet, nil
+		}
+	}
+	return nil, appError("You have several characters. Choose the one you play in this channel with `/roll character use <name>`, or roll with `/roll as <name> ...`.", nil)
+}
+
+// readCharacterName finds the character whose name starts a list of fields, trying the longest
+// names first, and returns it along with the remaining fields.
+func readCharacterName(characters map[string]characterSheet, fields []string) (*characterSheet, []string) {
+	for i := len(fields); i > 0; i-- {
+		if sheet, ok := characters[characterKey(strings.Join(fields[:i], " "))]; ok {
+			return &sheet, fields[i:]
+		}
+	}
+	return nil, fields
+}
+
+// executeCharacterCommand handles '/roll character list|use|delete'.
+func (p *Plugin) executeCharacterCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	action, name := splitSubcommand(query)
+	switch action {
+	case "list":
+		return p.executeListCharactersCommand(args)
+	case "use":
+		return p.executeUseCharacterCommand(args, name)
+	case "delete":
+		return p.executeDeleteCharacterCommand(args, name)
+	}
+	return nil, appError("Use `/roll character list`, `/roll character use <name>` or `/roll character delete <name>`.", nil)
+}
+
+func (p *Plugin) executeListCharactersCommand(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	characters, appErr := p.getCharacters(args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(characters) == 0 {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         "You have no characters. Import one with `/roll sheet import`.",
+		}, nil
+	}
+	var activeName string
+	if active, appErr := p.getActiveCharacter(args.UserId, args.ChannelId); appErr == nil {
+		activeName = active.Name
+	}
+
+	names := make([]string, 0, len(characters))
+	for _, sheet := range characters {
+		names = append(names, sheet.Name)
+	}
+	slices.SortFunc(names, func(a, b string) int { return strings.Compare(characterKey(a), characterKey(b)) })
+	text := "Your characters:"
+	for _, name := range names {
+		text += fmt.Sprintf("\n- **%s**", name)
+		if name == activeName {
+			text += " (active in this channel)"
+		}
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
+
+func (p *Plugin) executeUseCharacterCommand(args *model.CommandArgs, name string) (*model.CommandResponse, *model.AppError) {
+	if name == "" {
+		return nil, appError("Use `/roll character use <name>`, for example `/roll character use Aria`.", nil)
+	}
+	characters, appErr := p.getCharacters(args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	sheet, ok := characters[characterKey(name)]
+	if !ok {
+		return nil, appError(fmt.Sprintf("You have no character named %s. See your characters with `/roll character list`.", name), nil)
+	}
+	if appErr := kvSet(p, activeCharacterKey(args.UserId, args.ChannelId), characterKey(name)); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         fmt.Sprintf("**%s** is now your active character in this channel.", sheet.Name),
+	}, nil
+}
+
+func (p *Plugin) executeDeleteCharacterCommand(args *model.CommandArgs, name string) (*model.CommandResponse, *model.AppError) {
+	if name == "" {
+		return nil, appError("Use `/roll character delete <name>`, for example `/roll character delete Aria`.", nil)
+	}
+	key := characterKey(name)
+	var deleted characterSheet
+	if _, appErr := kvUpdate(p, charactersKey(args.UserId), func(characters *map[string]characterSheet) *model.AppError {
+		sheet, ok := (*characters)[key]
+		if !ok {
+			return appError(fmt.Sprintf("You have no character named %s.", name), nil)
+		}
+		deleted = sheet
+		delete(*characters, key)
+		return nil
+	}); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         fmt.Sprintf("**%s** is deleted.", deleted.Name),
+	}, nil
+}
+
+// executeAsCommand handles '/roll as <name> <query>', rolling the query, a check or a saving
+// throw as one of the characters of the user.
+func (p *Plugin) executeAsCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	characters, appErr := p.getCharacters(args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	sheet, fields := readCharacterName(characters, strings.Fields(query))
+	if sheet == nil {
+		return nil, appError("Use `/roll as <name> <dice>` with the name of one of your characters, for example `/roll as Aria 1d20+3` or `/roll as Aria check stealth`. See your characters with `/roll character list`.", nil)
+	}
+	if len(fields) == 0 {
+		return nil, appError(fmt.Sprintf("What does %s roll? For example `/roll as %s 1d20`.", sheet.Name, sheet.Name), nil)
+	}
+
+	switch subcommand, subquery := splitSubcommand(strings.Join(fields, " ")); subcommand {
+	case "check":
+		return p.executeSheetCheck(args, sheet, subquery)
+	case "save":
+		return p.executeSheetSave(args, sheet, subquery)
+	}
+	post, appErr := p.generateCharacterDicePost(sheet.Name, strings.Join(fields, " "), args.UserId, args.ChannelId, args.RootId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if _, appErr := p.createDicePost(post); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{}, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testStoredCharacters = `{"aria":` + testStoredSheet + `,"goblin boss":{"name":"Goblin Boss","abilities":{"dex":14,"str":10},"proficiency":2,"skills":["stealth"]}}`
+
+func TestReadCharacterName(t *testing.T) {
+	characters := map[string]characterSheet{
+		"goblin":      {Name: "Goblin"},
+		"goblin boss": {Name: "Goblin Boss"},
+	}
+	sheet, fields := readCharacterName(characters, []string{"goblin", "BOSS", "1d20"})
+	assert.Equal(t, "Goblin Boss", sheet.Name)
+	assert.Equal(t, []string{"1d20"}, fields)
+
+	sheet, fields = readCharacterName(characters, []string{"Goblin", "1d20"})
+	assert.Equal(t, "Goblin", sheet.Name)
+	assert.Equal(t, []string{"1d20"}, fields)
+
+	sheet, _ = readCharacterName(characters, []string{"orc", "1d20"})
+	assert.Nil(t, sheet)
+}
+
+func TestActiveCharacter(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", charactersKeyPrefix+"userid").Return([]byte(testStoredCharacters), nil)
+	api.On("KVGet", activeCharacterKeyPrefix+"channelid_userid").Return(nil, nil)
+	api.On("KVGet", activeCharacterKeyPrefix+"otherchannelid_userid").Return([]byte(`"goblin boss"`), nil)
+	api.On("KVSet", activeCharacterKeyPrefix+"channelid_userid", []byte(`"goblin boss"`)).Return(nil)
+
+	_, err := p.getActiveCharacter("userid", "channelid")
+	assert.Contains(t, err.Message, "You have several characters.")
+	sheet, err := p.getActiveCharacter("userid", "otherchannelid")
+	assert.Nil(t, err)
+	assert.Equal(t, "Goblin Boss", sheet.Name)
+
+	args := &model.CommandArgs{Command: "/roll character use goblin  boss", UserId: "userid", ChannelId: "channelid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "**Goblin Boss** is now your active character in this channel.", response.Text)
+
+	args = &model.CommandArgs{Command: "/roll character list", UserId: "userid", ChannelId: "otherchannelid"}
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "Your characters:\n- **Aria**\n- **Goblin Boss** (active in this channel)", response.Text)
+
+	for _, command := range []string{"/roll character", "/roll character use", "/roll character use Orc", "/roll character delete"} {
+		args.Command = command
+		response, err = p.ExecuteCommand(&plugin.Context{}, args)
+		assert.NotNil(t, err, command)
+		assert.Nil(t, response, command)
+	}
+}
+
+func TestDeleteCharacter(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", charactersKeyPrefix+"userid").Return([]byte(`{"aria":`+testStoredSheet+`}`), nil)
+	api.On("KVCompareAndSet", charactersKeyPrefix+"userid", []byte(`{"aria":`+testStoredSheet+`}`), []byte(`{}`)).Return(true, nil)
+	api.On("KVGet", charactersKeyPrefix+"otherid").Return(nil, nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll character delete ARIA", UserId: "userid", ChannelId: "channelid"})
+	assert.Nil(t, err)
+	assert.Equal(t, "**Aria** is deleted.", response.Text)
+
+	response, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll character list", UserId: "otherid", ChannelId: "channelid"})
+	assert.Nil(t, err)
+	assert.Equal(t, "You have no characters. Import one with `/roll sheet import`.", response.Text)
+}
+
+func TestRollAsCharacter(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	api.On("KVGet", charactersKeyPrefix+"userid").Return([]byte(testStoredCharacters), nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	args := &model.CommandArgs{Command: "/roll as Goblin Boss 2d1+3", UserId: "userid", ChannelId: "channelid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, "**Goblin Boss** rolls `2d1+3` = **8**\n- `2d1+3`: 4 4", post.Message)
+	assert.Equal(t, "userid", getRollData(post).UserID)
+
+	args.Command = "/roll as goblin boss check stealth"
+	_, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Regexp(t, `^\*\*Stealth check \(Dexterity \+2, proficiency \+2\)\*\*\n\*\*Goblin Boss\*\* rolls `, post.Message)
+
+	for _, command := range []string{"/roll as", "/roll as Orc 1d20", "/roll as Aria", "/roll as Aria save luck"} {
+		args.Command = command
+		response, err = p.ExecuteCommand(&plugin.Context{}, args)
+		assert.NotNil(t, err, command)
+		assert.Nil(t, response, command)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+const (
+	chartWidth  int = 640
+	chartHeight int = 320
+	chartMargin int = 16
+)
+
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartAxis       = color.RGBA{0x3f, 0x43, 0x50, 0xff}
+	chartGrid       = color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
+
+	// chartPalette are the (translucent) colors of the compared distributions,
+	// chartLegends the matching emojis used in the post text.
+	chartPalette = []color.NRGBA{
+		{0x1c, 0x58, 0xd9, 0x99},
+		{0xd2, 0x4b, 0x4e, 0x99},
+		{0x3d, 0xb8, 0x87, 0x99},
+		{0xff, 0xbc, 0x1f, 0x99},
+	}
+	chartLegends = []string{"🟦", "🟥", "🟩", "🟨"}
+)
+
+// drawDistributionChart renders the distributions as overlapping histograms sharing the
+// same axes, and returns the PNG encoded image.
+func drawDistributionChart(distributions []*dice.Distribution) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{chartBackground}, image.Point{}, draw.Src)
+
+	plot := image.Rect(chartMargin, chartMargin, chartWidth-chartMargin, chartHeight-chartMargin)
+	lowest, highest := distributions[0].Min, distributions[0].Max()
+	for _, dist := range distributions {
+		lowest = min(lowest, dist.Min)
+		highest = max(highest, dist.Max())
+	}
+	outcomes := highest - lowest + 1
+
+	// Each column of pixels shows the probability of the totals it covers
+	columns := make([][]float64, len(distributions))
+	maxValue := 0.0
+	for i, dist := range distributions {
+		columns[i] = make([]float64, plot.Dx())
+		for x := range columns[i] {
+			first, last := chartColumnOutcomes(x, plot.Dx(), outcomes)
+			for outcome := first; outcome <= last; outcome++ {
+				index := lowest + outcome - dist.Min
+				if index >= 0 && index < len(dist.Probs) {
+					columns[i][x] += dist.Probs[index]
+				}
+			}
+			maxValue = max(maxValue, columns[i][x])
+		}
+	}
+
+	for _, ratio := range []float64{0.25, 0.5, 0.75, 1} {
+		y := plot.Max.Y - int(ratio*float64(plot.Dy()))
+		draw.Draw(img, image.Rect(plot.Min.X, y, plot.Max.X, y+1), &image.Uniform{chartGrid}, image.Point{}, draw.Src)
+	}
+
+	for i := range distributions {
+		for x, value := range columns[i] {
+			first, _ := chartColumnOutcomes(x, plot.Dx(), outcomes)
+			// Leave a gap between the bars when they are wide enough
+			if outcomes*4 <= plot.Dx() && x > 0 {
+				if previous, _ := chartColumnOutcomes(x-1, plot.Dx(), outcomes); previous != first {
+					continue
+				}
+			}
+			height := 0
+			if maxValue > 0 {
+				height = int(value / maxValue * float64(plot.Dy()))
+			}
+			bar := image.Rect(plot.Min.X+x, plot.Max.Y-height, plot.Min.X+x+1, plot.Max.Y)
+			draw.Draw(img, bar, &image.Uniform{chartPalette[i]}, image.Point{}, draw.Over)
+		}
+	}
+
+	draw.Draw(img, image.Rect(plot.Min.X, plot.Max.Y, plot.Max.X, plot.Max.Y+1), &image.Uniform{chartAxis}, image.Point{}, draw.Src)
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// chartColumnOutcomes returns the range of outcome indexes displayed by a column of pixels.
+func chartColumnOutcomes(x, width, outcomes int) (int, int) {
+	first := x * outcomes / width
+	last := max(first, (x+1)*outcomes/width-1)
+	return first, last
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"path/filepath"
 	"reflect"
+	"strings"
+	"unicode"
 
 	manifest "github.com/moussetc/mattermost-plugin-dice-roller"
 
@@ -24,6 +26,19 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	// ShowRollStatistics adds the min, max, mean and percentile of the total to every roll
+	ShowRollStatistics bool
+	// SealedRollsTimeout is the delay, in minutes, after which sealed rolls are revealed
+	// automatically. Sealed rolls are only revealed by the game masters if it is 0.
+	SealedRollsTimeout int
+	// WebhookURLs are the URLs, one per line, notified of every public roll
+	WebhookURLs string
+	// WebhookSecret is the key used to sign the webhook payloads, if any
+	WebhookSecret string
+	// WebhookChannelIDs restricts the webhooks to the rolls of some channels, if any
+	WebhookChannelIDs string
+	// InlineRolls allows the channels to opt in to rolling the '[[1d20+5]]' expressions of the messages
+	InlineRolls bool
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -33,6 +48,19 @@ func (c *configuration) Clone() *configuration {
 	return &clone
 }
 
+// webhookURLs returns the configured webhook URLs.
+func (c *configuration) webhookURLs() []string {
+	return strings.Fields(c.WebhookURLs)
+}
+
+// webhookChannelIDs returns the channels whose rolls are sent to the webhooks,
+// or an empty list for all the channels.
+func (c *configuration) webhookChannelIDs() []string {
+	return strings.FieldsFunc(c.WebhookChannelIDs, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
 // getConfiguration retrieves the active configuration under lock, making it safe to use
 // concurrently. The active configuration may change underneath the client of this method, but
 // the struct returned by this API call is considered immutable.
@@ -88,10 +116,13 @@ func (p *Plugin) OnConfigurationChange() error {
 	return p.defineBot()
 }
 
+// botUsername is the username of the bot posting the rolls, which can also be mentioned to roll.
+const botUsername = "dicerollerbot"
+
 func (p *Plugin) defineBot() error {
 	client := pluginapi.NewClient(p.API, p.Driver)
 	bot := model.Bot{
-		Username:    "dicerollerbot",
+		Username:    botUsername,
 		DisplayName: "Dice Roller",
 		Description: "A bot account created by " + manifest.Manifest.Name + " plugin.",
 	}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+// maxSubcommandDistance is the maximum number of typos for a word to be taken for a subcommand.
+const maxSubcommandDistance int = 2
+
+// subcommands are the words starting the /roll subcommands, to suggest corrections of typos.
+var subcommands = []string{"help", "odds", "gm", "secret", "blind", "private", "whisper", "sealed", "reveal", "macro", "set", "unset", "vars", "sheet", "check", "save", "character", "as", "inline"}
+
+// parseErrorResponse explains to the user where the expression of their command is invalid,
+// with a caret under the error, and suggests a correction when one can be guessed.
+func parseErrorResponse(query string, parseErr *dice.ParseError) *model.CommandResponse {
+	message := parseErr.Error()
+	text := strings.ToUpper(message[:1]) + message[1:] + "."
+	padding := utf8.RuneCountInString(parseErr.Text[:parseErr.Position])
+	underline := max(1, utf8.RuneCountInString(parseErr.Text[parseErr.Position:parseErr.Position+parseErr.Length]))
+	text += fmt.Sprintf("\n```\n%s\n%s%s\n```", parseErr.Text, strings.Repeat(" ", padding), strings.Repeat("^", underline))
+	var variableErr *dice.UndefinedVariableError
+	if errors.As(parseErr, &variableErr) {
+		text += fmt.Sprintf("\nSet it with `/%s set %s <value>`.", trigger, variableErr.Name)
+	} else if suggestion := suggestCorrection(query, parseErr); suggestion != "" {
+		text += fmt.Sprintf("\nDid you mean `/%s %s`?", trigger, suggestion)
+	} else {
+		text += "\nSee `/roll help` for examples."
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}
+}
+
+// suggestCorrection guesses the query the user meant when the parse error is a typo,
+// or returns an empty string.
+func suggestCorrection(query string, parseErr *dice.ParseError) string {
+	// The expression is a part of the query, such as the query of a subcommand
+	start := strings.Index(query, parseErr.Text)
+	if start < 0 {
+		return ""
+	}
+	prefix, suffix := query[:start], query[start+len(parseErr.Text):]
+
+	var syntaxErr *dice.SyntaxError
+	if errors.As(parseErr, &syntaxErr) {
+		end := parseErr.Offset + len(parseErr.Code)
+		for _, candidate := range correctionCandidates(parseErr.Code, syntaxErr.Position, parseErr.Text[end:]) {
+			if _, err := dice.ParseRequest(candidate.code); err == nil {
+				return prefix + parseErr.Text[:parseErr.Offset] + candidate.code + parseErr.Text[end+candidate.consumed:] + suffix
+			}
+		}
+	}
+
+	// Only a word can be a typo of a subcommand, not a mistyped die code such as '1dd6' or 'ddd'
+	if prefix == "" && parseErr.Offset == 0 && isWord(parseErr.Code) && strings.Trim(strings.ToLower(parseErr.Code), "d") != "" {
+		if subcommand := closestSubcommand(parseErr.Code); subcommand != "" {
+			return subcommand + query[len(parseErr.Code):]
+		}
+	}
+	return ""
+}
+
+// isWord tells whether a text is only made of letters.
+func isWord(text string) bool {
+	return text != "" && strings.IndexFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) < 0
+}
+
+// correction is a corrected roll request, replacing the invalid one and the first consumed
+// bytes of the rest of the expression.
+type correction struct {
+	code     string
+	consumed int
+}
+
+// correctionCandidates lists corrections of common typos of a roll request with a syntax error
+// at the given position, such as '2x6', '0d5', '2d6+' or '+ 3'.
+func correctionCandidates(code string, position int, rest string) []correction {
+	candidates := []correction{}
+	if position < len(code) {
+		candidates = append(candidates,
+			correction{code: code[:position] + "d" + code[position+1:]},
+			correction{code: code[:position] + code[position+1:]},
+		)
+	}
+	if code == "+" || code == "-" {
+		next := strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if end := strings.IndexFunc(next, unicode.IsSpace); end >= 0 {
+			next = next[:end]
+		}
+		consumed := strings.Index(rest, next) + len(next)
+		candidates = append(candidates, correction{code: code + next, consumed: consumed})
+	} else if strings.HasSuffix(code, "+") || strings.HasSuffix(code, "-") {
+		candidates = append(candidates, correction{code: code[:len(code)-1]})
+	}
+	return candidates
+}
+
+// closestSubcommand returns the subcommand the word is a typo of, or an empty string.
+func closestSubcommand(word string) string {
+	closest, closestDistance := "", maxSubcommandDistance+1
+	for _, subcommand := range subcommands {
+		distance := levenshtein(strings.ToLower(word), subcommand)
+		if distance < closestDistance && distance < len(subcommand) {
+			closest, closestDistance = subcommand, distance
+		}
+	}
+	return closest
+}
+
+// levenshtein returns the minimum number of single character edits to change a into b.
+func levenshtein(a, b string) int {
+	source, target := []rune(a), []rune(b)
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := range source {
+		current[0] = i + 1
+		for j := range target {
+			cost := 1
+			if source[i] == target[j] {
+				cost = 0
+			}
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(target)]
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrorResponse(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestMacros(api, "", "", "")
+
+	testCases := []struct {
+		command string
+		text    string
+	}{
+		{
+			command: "/roll 2d6 6d",
+			text:    "'6d' is not a valid die code: expected a number of sides.\n```\n2d6 6d\n      ^\n```\nSee `/roll help` for examples.",
+		},
+		{
+			command: "/roll 101d6",
+			text:    "'101' is too many dice; maximum is 100.\n```\n101d6\n^^^^^\n```\nSee `/roll help` for examples.",
+		},
+		{command: "/roll 2x6 +1", text: "Did you mean `/roll 2d6 +1`?"},
+		{command: "/roll 0d5", text: "Did you mean `/roll d5`?"},
+		{command: "/roll 1dd6 +2", text: "Did you mean `/roll 1d6 +2`?"},
+		{command: "/roll 2dd6", text: "Did you mean `/roll 2d6`?"},
+		{command: "/roll 1d20+", text: "Did you mean `/roll 1d20`?"},
+		{command: "/roll d20 + 5", text: "Did you mean `/roll d20 +5`?"},
+		{command: "/roll ods 2d6 vs 1d12", text: "Did you mean `/roll odds 2d6 vs 1d12`?"},
+		{command: "/roll odds 2d6 vs 3w6", text: "Did you mean `/roll odds 2d6 vs 3d6`?"},
+		{command: "/roll private d20 2d6x", text: "Did you mean `/roll private d20 2d6`?"},
+		{command: "/roll ddd", text: "See `/roll help` for examples."},
+		{command: "/roll hahaha", text: "See `/roll help` for examples."},
+	}
+	for _, testCase := range testCases {
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: testCase.command, UserId: "userid", ChannelId: "channelid"})
+		assert.Nil(t, err, testCase.command)
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType, testCase.command)
+		assert.Contains(t, response.Text, testCase.text, testCase.command)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("odds", "odds"))
+	assert.Equal(t, 1, levenshtein("ods", "odds"))
+	assert.Equal(t, 2, levenshtein("sceret", "secret"))
+	assert.Equal(t, 4, levenshtein("", "help"))
+	assert.Equal(t, "whisper", closestSubcommand("Wisper"))
+	assert.Equal(t, "", closestSubcommand("d"))
+}
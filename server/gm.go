@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	channelMembersPerPage int = 200
+	// gmsKeyPrefix prefixes the KV store keys of the game masters designated for a channel,
+	// followed by the channel ID.
+	gmsKeyPrefix = "gms_"
+)
+
+// getChannelGMs returns the IDs of the game masters of a channel: the users designated
+// with '/roll gm set', or the channel admins if there are none.
+func (p *Plugin) getChannelGMs(channelID string) ([]string, *model.AppError) {
+	gmIDs, appErr := p.getDesignatedGMs(channelID)
+	if appErr != nil || len(gmIDs) > 0 {
+		return gmIDs, appErr
+	}
+	return p.getChannelAdmins(channelID)
+}
+
+func (p *Plugin) getDesignatedGMs(channelID string) ([]string, *model.AppError) {
+	gmIDs, appErr := kvGet[[]string](p, gmsKeyPrefix+channelID)
+	if appErr != nil || gmIDs == nil {
+		return nil, appErr
+	}
+	return *gmIDs, nil
+}
+
+func (p *Plugin) setDesignatedGMs(channelID string, gmIDs []string) *model.AppError {
+	if len(gmIDs) == 0 {
+		return p.API.KVDelete(gmsKeyPrefix + channelID)
+	}
+	return kvSet(p, gmsKeyPrefix+channelID, gmIDs)
+}
+
+func (p *Plugin) getChannelAdmins(channelID string) ([]string, *model.AppError) {
+	adminIDs := []string{}
+	for page := 0; ; page++ {
+		members, appErr := p.API.GetChannelMembers(channelID, page, channelMembersPerPage)
+		if appErr != nil {
+			return nil, appErr
+		}
+		for _, member := range members {
+			if member.SchemeAdmin {
+				adminIDs = append(adminIDs, member.UserId)
+			}
+		}
+		if len(members) < channelMembersPerPage {
+			return adminIDs, nil
+		}
+	}
+}
+
+// isChannelAdmin checks whether a user is an admin of a channel, or a system admin.
+func (p *Plugin) isChannelAdmin(channelID, userID string) (bool, *model.AppError) {
+	if p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		return true, nil
+	}
+	member, appErr := p.API.GetChannelMember(channelID, userID)
+	if appErr != nil {
+		if appErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, appErr
+	}
+	return member.SchemeAdmin, nil
+}
+
+// canManageGame checks whether a user can run the game of a channel (reveal rolls,
+// manage the table...): the game masters and the channel admins can.
+func (p *Plugin) canManageGame(channelID, userID string) (bool, *model.AppError) {
+	gmIDs, appErr := p.getChannelGMs(channelID)
+	if appErr != nil {
+		return false, appErr
+	}
+	if slices.Contains(gmIDs, userID) {
+		return true, nil
+	}
+	return p.isChannelAdmin(channelID, userID)
+}
+
+// executeGMCommand handles '/roll gm set @user...' and '/roll gm list',
+// and makes a secret roll otherwise.
+func (p *Plugin) executeGMCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	switch action, mentions := splitSubcommand(query); action {
+	case "set":
+		return p.executeSetGMsCommand(args, mentions)
+	case "list":
+		return p.executeListGMsCommand(args)
+	default:
+		return p.executeSecretRollCommand(args, query)
+	}
+}
+
+func (p *Plugin) executeSetGMsCommand(args *model.CommandArgs, mentions string) (*model.CommandResponse, *model.AppError) {
+	isAdmin, appErr := p.isChannelAdmin(args.ChannelId, args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !isAdmin {
+		return nil, appError("Only the channel admins can choose the game masters.", nil)
+	}
+
+	users, rest, appErr := p.readMentions(strings.Fields(mentions))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(rest) > 0 {
+		return nil, appError("Mention the game masters, for example `/roll gm set @alice @bob`.", nil)
+	}
+	gmIDs := []string{}
+	for _, user := range users {
+		if _, appErr := p.API.GetChannelMember(args.ChannelId, user.Id); appErr != nil {
+			return nil, appError(fmt.Sprintf("@%s is not a member of this channel.", user.Username), appErr)
+		}
+		gmIDs = append(gmIDs, user.Id)
+	}
+	if appErr := p.setDesignatedGMs(args.ChannelId, gmIDs); appErr != nil {
+		return nil, appErr
+	}
+
+	text := "The game masters of this channel are now its channel admins."
+	if len(users) > 0 {
+		text = "The game masters of this channel are now " + formatUsernames(users) + "."
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
+
+func (p *Plugin) executeListGMsCommand(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	designatedIDs, appErr := p.getDesignatedGMs(args.ChannelId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	gmIDs, appErr := p.getChannelGMs(args.ChannelId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(gmIDs) == 0 {
+		return &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         "This channel has no game master. A channel admin can choose them with `/roll gm set @user`.",
+		}, nil
+	}
+
+	users := make([]*model.User, len(gmIDs))
+	for i, gmID := range gmIDs {
+		user, appErr := p.API.GetUser(gmID)
+		if appErr != nil {
+			return nil, appErr
+		}
+		users[i] = user
+	}
+	text := "The game masters of this channel are " + formatUsernames(users) + "."
+	if len(designatedIDs) == 0 {
+		text = "The game masters of this channel are its channel admins: " + formatUsernames(users) + "."
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
+
+// sendToGMs sends a direct message from the dice bot to every game master of a channel,
+// such as "Secret roll in **Campaign**:" followed by the message. A failed message does not
+// prevent the other game masters from receiving theirs: the game masters who did not get it are
+// returned, and it only fails when none did.
+func (p *Plugin) sendToGMs(gmIDs []string, channelID, label, message string) ([]string, *model.AppError) {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	var failedIDs []string
+	var errs []error
+	for _, gmID := range gmIDs {
+		if appErr := p.sendToGM(gmID, fmt.Sprintf("%s in **%s**:\n%s", label, channel.DisplayName, message)); appErr != nil {
+			p.API.LogError("Failed to send a roll to a game master", "user_id", gmID, "channel_id", channelID, "error", appErr.Error())
+			failedIDs = append(failedIDs, gmID)
+			errs = append(errs, fmt.Errorf("game master %s: %w", gmID, appErr))
+		}
+	}
+	if len(failedIDs) == len(gmIDs) {
+		return failedIDs, appError("The roll could not be sent to any game master.", errors.Join(errs...))
+	}
+	return failedIDs, nil
+}
+
+// warnFailedGMs tells the roller which game masters did not receive their roll.
+func (p *Plugin) warnFailedGMs(args *model.CommandArgs, failedIDs []string) {
+	if len(failedIDs) == 0 {
+		return
+	}
+	names := make([]string, len(failedIDs))
+	for i, gmID := range failedIDs {
+		names[i] = gmID
+		if displayName, appErr := p.getDisplayName(gmID); appErr == nil {
+			names[i] = displayName
+		}
+	}
+	p.API.SendEphemeralPost(args.UserId, &model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   fmt.Sprintf("Your roll could not be sent to the game masters %s.", strings.Join(names, ", ")),
+	})
+}
+
+func (p *Plugin) sendToGM(gmID, message string) *model.AppError {
+	dm, appErr := p.API.GetDirectChannel(p.diceBotID, gmID)
+	if appErr != nil {
+		return appErr
+	}
+	_, appErr = p.API.CreatePost(&model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: dm.Id,
+		Message:   message,
+	})
+	return appErr
+}
+
+// executeSecretRollCommand shows the roll to the roller and the game masters only,
+// while the channel is only told that a secret roll was made.
+func (p *Plugin) executeSecretRollCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	gmIDs, appErr := p.getChannelGMs(args.ChannelId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(gmIDs) == 0 {
+		return nil, appError("There is no game master in this channel to send the secret roll to.", nil)
+	}
+
+	post, appErr := p.generateDicePost(query, args.UserId, args.ChannelId, args.RootId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	failedIDs, appErr := p.sendToGMs(gmIDs, args.ChannelId, "Secret roll", post.Message)
+	if appErr != nil {
+		return nil, appErr
+	}
+	p.warnFailedGMs(args, failedIDs)
+	// Rolling again from an ephemeral post would not be secret anymore
+	post.DelProp(model.PostPropsAttachments)
+	p.API.SendEphemeralPost(args.UserId, post)
+
+	displayName, appErr := p.getDisplayName(args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if _, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   fmt.Sprintf("**%s** made a secret roll.", displayName),
+	}); appErr != nil {
+		return nil, appErr
+	}
+
+	return &model.CommandResponse{}, nil
+}
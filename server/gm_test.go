@@ -0,0 +1,182 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretRoll(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	initTestGM(api)
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", DisplayName: "Campaign"}, nil)
+	api.On("GetDirectChannel", "botid", "gmid").Return(&model.Channel{Id: "dmid"}, nil)
+	posts := map[string]*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
+		post := args.Get(0).(*model.Post)
+		posts[post.ChannelId] = post
+	})
+	var ephemeralPost *model.Post
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		ephemeralPost = args.Get(1).(*model.Post)
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll gm 3d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, "**User** made a secret roll.", posts["channelid"].Message)
+	assert.Equal(t, "Secret roll in **Campaign**:\n**User** rolls `3d1` = **3**\n- `3d1`: 1 1 1", posts["dmid"].Message)
+	assert.Equal(t, "botid", posts["dmid"].UserId)
+	assert.NotNil(t, ephemeralPost)
+	assert.Equal(t, "**User** rolls `3d1` = **3**\n- `3d1`: 1 1 1", ephemeralPost.Message)
+}
+
+func TestSecretRollWithoutGM(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", gmsKeyPrefix+"channelid").Return(nil, nil)
+	api.On("GetChannelMembers", "channelid", 0, channelMembersPerPage).Return(model.ChannelMembers{{UserId: "userid"}}, nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll secret d20",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+}
+
+func TestSendToGMsWithFailure(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", DisplayName: "Campaign"}, nil)
+	api.On("GetDirectChannel", "botid", "gm1").Return(nil, &model.AppError{Message: "no DM"})
+	api.On("GetDirectChannel", "botid", "gm2").Return(&model.Channel{Id: "dm2"}, nil)
+	api.On("GetDirectChannel", "botid", "gm3").Return(&model.Channel{Id: "dm3"}, nil)
+	api.On("LogError", "Failed to send a roll to a game master", "user_id", "gm1", "channel_id", "channelid", "error", mock.Anything).Return()
+	posts := map[string]*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
+		post := args.Get(0).(*model.Post)
+		posts[post.ChannelId] = post
+	})
+
+	failedIDs, err := p.sendToGMs([]string{"gm1", "gm2", "gm3"}, "channelid", "Secret roll", "**User** rolls `d1` = **1**")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"gm1"}, failedIDs)
+	assert.Len(t, posts, 2)
+	assert.Equal(t, "Secret roll in **Campaign**:\n**User** rolls `d1` = **1**", posts["dm2"].Message)
+	assert.Equal(t, "Secret roll in **Campaign**:\n**User** rolls `d1` = **1**", posts["dm3"].Message)
+	api.AssertCalled(t, "LogError", "Failed to send a roll to a game master", "user_id", "gm1", "channel_id", "channelid", "error", mock.Anything)
+
+	// It only fails when no game master got the roll
+	failedIDs, err = p.sendToGMs([]string{"gm1"}, "channelid", "Secret roll", "**User** rolls `d1` = **1**")
+	assert.NotNil(t, err)
+	assert.Equal(t, "The roll could not be sent to any game master.", err.Message)
+	assert.Contains(t, err.DetailedError, "gm1")
+	assert.Equal(t, []string{"gm1"}, failedIDs)
+}
+
+func TestSecretRollWithFailedGM(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	api.On("KVGet", gmsKeyPrefix+"channelid").Return([]byte(`["gmid","othergmid"]`), nil)
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", DisplayName: "Campaign"}, nil)
+	api.On("GetDirectChannel", "botid", "gmid").Return(&model.Channel{Id: "dmid"}, nil)
+	api.On("GetDirectChannel", "botid", "othergmid").Return(nil, &model.AppError{Message: "no DM"})
+	api.On("LogError", "Failed to send a roll to a game master", "user_id", "othergmid", "channel_id", "channelid", "error", mock.Anything).Return()
+	posts := map[string]*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
+		post := args.Get(0).(*model.Post)
+		posts[post.ChannelId] = post
+	})
+	var ephemeralPosts []*model.Post
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		ephemeralPosts = append(ephemeralPosts, args.Get(1).(*model.Post))
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll secret 3d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.NotNil(t, posts["dmid"])
+	assert.Equal(t, "**User** made a secret roll.", posts["channelid"].Message)
+	assert.Len(t, ephemeralPosts, 2)
+	assert.Equal(t, "Your roll could not be sent to the game masters User.", ephemeralPosts[0].Message)
+}
+
+func TestSetGMs(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestGM(api)
+	api.On("GetUserByUsername", "user").Return(&model.User{Id: "userid", Username: "user"}, nil)
+	api.On("KVSet", gmsKeyPrefix+"channelid", []byte(`["userid"]`)).Return(nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll gm set @user",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+
+	response, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll gm set @user",
+		UserId:    "gmid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "The game masters of this channel are now @user.", response.Text)
+	api.AssertCalled(t, "KVSet", gmsKeyPrefix+"channelid", []byte(`["userid"]`))
+}
+
+func TestResetGMs(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestGM(api)
+	api.On("KVDelete", gmsKeyPrefix+"channelid").Return(nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll gm set",
+		UserId:    "gmid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "The game masters of this channel are now its channel admins.", response.Text)
+	api.AssertCalled(t, "KVDelete", gmsKeyPrefix+"channelid")
+}
+
+func TestListGMs(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", gmsKeyPrefix+"channelid").Return([]byte(`["userid"]`), nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll gm list",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "The game masters of this channel are @user.", response.Text)
+}
+
+func TestCanManageGame(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", gmsKeyPrefix+"channelid").Return([]byte(`["designatedid"]`), nil)
+	api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false)
+	api.On("GetChannelMember", "channelid", "gmid").Return(&model.ChannelMember{SchemeAdmin: true}, nil)
+	api.On("GetChannelMember", "channelid", "userid").Return(&model.ChannelMember{}, nil)
+
+	for userID, expected := range map[string]bool{"designatedid": true, "gmid": true, "userid": false} {
+		canManage, err := p.canManageGame("channelid", userID)
+		assert.Nil(t, err, userID)
+		assert.Equal(t, expected, canManage, userID)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	manifest "github.com/moussetc/mattermost-plugin-dice-roller"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+const (
+	// headerUserID is set by the Mattermost server to the ID of the authenticated user.
+	headerUserID = "Mattermost-User-ID"
+	// headerPluginID is set by the Mattermost server to the ID of the plugin making an inter-plugin request.
+	headerPluginID = "Mattermost-Plugin-ID"
+)
+
+func (p *Plugin) initRouter() *http.ServeMux {
+	router := http.NewServeMux()
+	router.HandleFunc("POST /api/v1/blind/reveal", p.handleRevealBlindRoll)
+	router.HandleFunc("POST /api/v1/actions/roll", p.handleRollAction)
+	router.HandleFunc("POST /api/v1/roll", p.handleRoll)
+	router.HandleFunc("POST /roll/evaluate", p.handleEvaluate)
+	return router
+}
+
+// ServeHTTP handles the HTTP requests sent to /plugins/{id}, such as the post actions callbacks.
+func (p *Plugin) ServeHTTP(_ *plugin.Context, w http.ResponseWriter, r *http.Request) {
+	p.router.ServeHTTP(w, r)
+}
+
+// pluginURL returns the URL, relative to the server, of a route served by the plugin.
+func pluginURL(path string) string {
+	return "/plugins/" + manifest.Manifest.Id + path
+}
+
+// readActionRequest decodes a post action callback, made on behalf of an authenticated user.
+func readActionRequest(w http.ResponseWriter, r *http.Request) (*model.PostActionIntegrationRequest, bool) {
+	userID := r.Header.Get(headerUserID)
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	// Trust the server's header over the body
+	request.UserId = userID
+	return &request, true
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// apiError is the body of the error responses of the REST API.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(&apiError{Error: message})
+}
+
+// writeActionResponse answers a post action callback, with an optional ephemeral message
+// shown to the user who clicked.
+func writeActionResponse(w http.ResponseWriter, ephemeralText string) {
+	writeJSON(w, &model.PostActionIntegrationResponse{EphemeralText: ephemeralText})
+}
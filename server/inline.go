@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+const (
+	// inlineRollsKeyPrefix prefixes the KV store keys telling whether a channel opted in to the
+	// inline rolls, followed by the channel ID.
+	inlineRollsKeyPrefix string = "inlinerolls_"
+	// maxInlineRolls is the maximum number of inline rolls replaced in a message.
+	maxInlineRolls int = 20
+)
+
+// inlineRollRegexp matches the inline rolls of a message, such as '[[1d20+5]]'.
+var inlineRollRegexp = regexp.MustCompile(`\[\[([^\[\]]+)\]\]`)
+
+// MessageWillBePosted replaces the inline rolls of the messages, such as '[[1d20+5]]', with their
+// results when the channel opted in.
+func (p *Plugin) MessageWillBePosted(_ *plugin.Context, post *model.Post) (*model.Post, string) {
+	if !p.getConfiguration().InlineRolls || post.UserId == p.diceBotID || post.IsSystemMessage() || !inlineRollRegexp.MatchString(post.Message) {
+		return nil, ""
+	}
+	enabled, appErr := p.areInlineRollsEnabled(post.ChannelId)
+	if appErr != nil {
+		p.API.LogWarn("Failed to read the inline rolls setting of a channel", "channel_id", post.ChannelId, "error", appErr.Error())
+		return nil, ""
+	}
+	if !enabled {
+		return nil, ""
+	}
+	post.Message = p.replaceInlineRolls(post.UserId, post.ChannelId, post.Message)
+	return post, ""
+}
+
+// replaceInlineRolls replaces the inline rolls of a message with their results, such as
+// '`2d6+1` = **9** (`2d6+1`: 4 5)'. Invalid expressions are left as they are.
+func (p *Plugin) replaceInlineRolls(userID, channelID, message string) string {
+	count := 0
+	return inlineRollRegexp.ReplaceAllStringFunc(message, func(span string) string {
+		if count >= maxInlineRolls {
+			return span
+		}
+		query := strings.TrimSpace(inlineRollRegexp.FindStringSubmatch(span)[1])
+		expression, appErr := p.parseUserExpression(userID, channelID, query)
+		if appErr != nil {
+			return span
+		}
+		count++
+		result := roller.Roll(expression)
+		text := fmt.Sprintf("%s = **%d**", dice.Code(query), result.Total)
+		if result.HasDetails() {
+			text += fmt.Sprintf(" (%s)", strings.Join(result.Details(), ", "))
+		}
+		return text
+	})
+}
+
+func (p *Plugin) areInlineRollsEnabled(channelID string) (bool, *model.AppError) {
+	enabled, appErr := kvGet[bool](p, inlineRollsKeyPrefix+channelID)
+	if appErr != nil || enabled == nil {
+		return false, appErr
+	}
+	return *enabled, nil
+}
+
+// executeInlineCommand handles '/roll inline on|off', opting the channel in or out of the inline rolls.
+func (p *Plugin) executeInlineCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	if !p.getConfiguration().InlineRolls {
+		return nil, appError("Inline rolls are disabled in the plugin settings.", nil)
+	}
+	if query != "on" && query != "off" {
+		return nil, appError("Use `/roll inline on` or `/roll inline off`.", nil)
+	}
+	canManage, appErr := p.canManageGame(args.ChannelId, args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !canManage {
+		return nil, appError("Only the game masters and the channel admins can turn the inline rolls on or off.", nil)
+	}
+
+	text := "Inline rolls are now off in this channel."
+	if query == "on" {
+		appErr = kvSet(p, inlineRollsKeyPrefix+args.ChannelId, true)
+		text = "Inline rolls are now on in this channel: write `[[1d20+5]]` in a message to roll it."
+	} else {
+		appErr = p.API.KVDelete(inlineRollsKeyPrefix + args.ChannelId)
+	}
+	if appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
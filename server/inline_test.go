@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInlineRolls(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	p.setConfiguration(&configuration{InlineRolls: true})
+	initTestMacros(api, `{"attack":"1d1+4"}`, "", "")
+	api.On("KVGet", inlineRollsKeyPrefix+"channelid").Return([]byte("true"), nil)
+	api.On("KVGet", inlineRollsKeyPrefix+"otherid").Return(nil, nil)
+
+	post, reason := p.MessageWillBePosted(&plugin.Context{}, &model.Post{
+		UserId:    "userid",
+		ChannelId: "channelid",
+		Message:   "I jump [[ 2d1+1 ]] and strike [[attack]]! [[hahaha]] [not a roll]",
+	})
+	assert.Equal(t, "", reason)
+	assert.Equal(t, "I jump `2d1+1` = **4** (`2d1+1`: 2 2) and strike `attack` = **5**! [[hahaha]] [not a roll]", post.Message)
+
+	for _, ignored := range []*model.Post{
+		{UserId: "userid", ChannelId: "channelid", Message: "No roll [here]"},
+		{UserId: "botid", ChannelId: "channelid", Message: "[[1d20]]"},
+		{UserId: "userid", ChannelId: "otherid", Message: "[[1d20]]"},
+	} {
+		post, reason = p.MessageWillBePosted(&plugin.Context{}, ignored)
+		assert.Nil(t, post, ignored.Message)
+		assert.Equal(t, "", reason)
+	}
+
+	p.setConfiguration(&configuration{})
+	post, _ = p.MessageWillBePosted(&plugin.Context{}, &model.Post{UserId: "userid", ChannelId: "channelid", Message: "[[1d20]]"})
+	assert.Nil(t, post)
+}
+
+func TestInlineRollsLimit(t *testing.T) {
+	p, _ := initTestPlugin()
+	message := ""
+	for range maxInlineRolls + 1 {
+		message += "[[1]]"
+	}
+	replaced := p.replaceInlineRolls("userid", "channelid", message)
+	assert.Equal(t, maxInlineRolls, strings.Count(replaced, "`1` = **1**"))
+	assert.True(t, strings.HasSuffix(replaced, "`1` = **1**[[1]]"))
+}
+
+func TestInlineCommand(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestGM(api)
+	api.On("KVSet", inlineRollsKeyPrefix+"channelid", []byte("true")).Return(nil)
+	api.On("KVDelete", inlineRollsKeyPrefix+"channelid").Return(nil)
+	args := &model.CommandArgs{Command: "/roll inline on", UserId: "gmid", ChannelId: "channelid"}
+
+	_, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Equal(t, "Inline rolls are disabled in the plugin settings.", err.Message)
+
+	p.setConfiguration(&configuration{InlineRolls: true})
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "Inline rolls are now on in this channel")
+
+	args.Command = "/roll inline off"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "Inline rolls are now off in this channel.", response.Text)
+
+	for _, command := range []string{"/roll inline", "/roll inline maybe"} {
+		args.Command = command
+		_, err = p.ExecuteCommand(&plugin.Context{}, args)
+		assert.NotNil(t, err, command)
+	}
+	args = &model.CommandArgs{Command: "/roll inline on", UserId: "userid", ChannelId: "channelid"}
+	_, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Equal(t, "Only the game masters and the channel admins can turn the inline rolls on or off.", err.Message)
+}
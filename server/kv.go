@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// kvMaxAttempts limits the retries of an atomic update conflicting with concurrent updates.
+	kvMaxAttempts int = 5
+)
+
+// kvGet reads the JSON value stored in the KV store for a key, or nil if the key does not exist.
+func kvGet[T any](p *Plugin, key string) (*T, *model.AppError) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil || data == nil {
+		return nil, appErr
+	}
+	value := new(T)
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, appError("Could not read the stored data.", err)
+	}
+	return value, nil
+}
+
+// kvSet stores a value as JSON in the KV store.
+func kvSet(p *Plugin, key string, value any) *model.AppError {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return appError("Could not save the data.", err)
+	}
+	return p.API.KVSet(key, data)
+}
+
+// kvUpdate atomically updates the JSON value stored in the KV store for a key, retrying
+// when the value was changed concurrently. The update function receives the zero value when
+// the key does not exist, and must not have side effects as it may be called several times.
+func kvUpdate[T any](p *Plugin, key string, update func(value *T) *model.AppError) (*T, *model.AppError) {
+	for attempt := 0; attempt < kvMaxAttempts; attempt++ {
+		oldData, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return nil, appErr
+		}
+		value := new(T)
+		if oldData != nil {
+			if err := json.Unmarshal(oldData, value); err != nil {
+				return nil, appError("Could not read the stored data.", err)
+			}
+		}
+		if appErr := update(value); appErr != nil {
+			return nil, appErr
+		}
+		newData, err := json.Marshal(value)
+		if err != nil {
+			return nil, appError("Could not save the data.", err)
+		}
+		saved, appErr := p.API.KVCompareAndSet(key, oldData, newData)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if saved {
+			return value, nil
+		}
+	}
+	return nil, appError("Could not save the data because of concurrent changes, please try again.", nil)
+}
+
+// kvTake atomically reads and deletes the JSON value stored in the KV store for a key.
+// It returns nil if the key does not exist.
+func kvTake[T any](p *Plugin, key string) (*T, *model.AppError) {
+	for attempt := 0; attempt < kvMaxAttempts; attempt++ {
+		data, appErr := p.API.KVGet(key)
+		if appErr != nil || data == nil {
+			return nil, appErr
+		}
+		deleted, appErr := p.API.KVCompareAndDelete(key, data)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if deleted {
+			value := new(T)
+			if err := json.Unmarshal(data, value); err != nil {
+				return nil, appError("Could not read the stored data.", err)
+			}
+			return value, nil
+		}
+	}
+	return nil, appError("Could not read the data because of concurrent changes, please try again.", nil)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+const (
+	// userMacrosKeyPrefix, channelMacrosKeyPrefix and teamMacrosKeyPrefix prefix the KV store
+	// keys of the macros of a user, a channel or a team, followed by its ID.
+	userMacrosKeyPrefix    string = "macros_"
+	channelMacrosKeyPrefix string = "channelmacros_"
+	teamMacrosKeyPrefix    string = "teammacros_"
+	// maxMacroDepth limits the nesting of macros using other macros, which also stops
+	// macros using themselves.
+	maxMacroDepth      int = 5
+	maxMacroNameLength int = 32
+)
+
+var (
+	macroNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	// <macro name><optional modifiers added to the total>, such as 'attack+2' or 'attack+@prof'
+	macroCallRegexp = regexp.MustCompile(`^(?P<name>[a-zA-Z][a-zA-Z0-9_]*)(?P<modifier>([+-](\d+|@[a-z_][a-z0-9_]*))*)$`)
+)
+
+// macroScope is a namespace of macros: the personal macros of a user, or the macros shared
+// in a channel or a team.
+type macroScope struct {
+	key string
+	// name describes a macro of the scope, such as "Channel macro", and title lists them
+	name  string
+	title string
+	// has starts the sentences about the macros of the scope, such as "This channel has"
+	has string
+}
+
+func userMacroScope(userID string) *macroScope {
+	return &macroScope{key: userMacrosKeyPrefix + userID, name: "Macro", title: "Your macros", has: "You have"}
+}
+
+func channelMacroScope(channelID string) *macroScope {
+	return &macroScope{key: channelMacrosKeyPrefix + channelID, name: "Channel macro", title: "Channel macros", has: "This channel has"}
+}
+
+func teamMacroScope(teamID string) *macroScope {
+	return &macroScope{key: teamMacrosKeyPrefix + teamID, name: "Team macro", title: "Team macros", has: "This team has"}
+}
+
+func (p *Plugin) getScopeMacros(scope *macroScope) (map[string]string, *model.AppError) {
+	macros, appErr := kvGet[map[string]string](p, scope.key)
+	if appErr != nil || macros == nil {
+		return map[string]string{}, appErr
+	}
+	return *macros, nil
+}
+
+// getMacroScopes returns the scopes of the macros available to a user in a team and a channel,
+// by increasing precedence: the personal macros override the channel macros, which override
+// the team macros.
+func getMacroScopes(userID, channelID, teamID string) []*macroScope {
+	scopes := []*macroScope{}
+	if teamID != "" {
+		scopes = append(scopes, teamMacroScope(teamID))
+	}
+	return append(scopes, channelMacroScope(channelID), userMacroScope(userID))
+}
+
+// getMacros returns the macros available to a user in a team and a channel.
+func (p *Plugin) getMacros(userID, channelID, teamID string) (map[string]string, *model.AppError) {
+	macros := map[string]string{}
+	for _, scope := range getMacroScopes(userID, channelID, teamID) {
+		scopeMacros, appErr := p.getScopeMacros(scope)
+		if appErr != nil {
+			return nil, appErr
+		}
+		maps.Copy(macros, scopeMacros)
+	}
+	return macros, nil
+}
+
+// expandMacros replaces the macros of a query with their expressions, such as 'attack+2'
+// with '1d20+7 +2'. The query is returned as is when it has no macro.
+func (p *Plugin) expandMacros(userID, channelID, query string) (string, *model.AppError) {
+	if !slices.ContainsFunc(strings.Fields(query), isMacroCall) {
+		return query, nil
+	}
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return "", appErr
+	}
+	macros, appErr := p.getMacros(userID, channelID, channel.TeamId)
+	if appErr != nil {
+		return "", appErr
+	}
+	return expandMacroQuery(query, macros, 0)
+}
+
+// hasMacro tells whether a macro is available to a user in a channel.
+func (p *Plugin) hasMacro(userID, channelID, name string) (bool, *model.AppError) {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return false, appErr
+	}
+	macros, appErr := p.getMacros(userID, channelID, channel.TeamId)
+	if appErr != nil {
+		return false, appErr
+	}
+	_, ok := macros[name]
+	return ok, nil
+}
+
+func expandMacroQuery(query string, macros map[string]string, depth int) (string, *model.AppError) {
+	fields := strings.Fields(query)
+	for i, field := range fields {
+		if !isMacroCall(field) {
+			continue
+		}
+		matches := macroCallRegexp.FindStringSubmatch(field)
+		name := strings.ToLower(matches[macroCallRegexp.SubexpIndex("name")])
+		expression, ok := macros[name]
+		if !ok {
+			// Leave unknown names to the parser, which explains the error
+			continue
+		}
+		if depth >= maxMacroDepth {
+			return "", appError(fmt.Sprintf("Macros are nested too deeply (maximum %d levels), check that the macro `%s` does not use itself.", maxMacroDepth, name), nil)
+		}
+		expanded, appErr := expandMacroQuery(expression, macros, depth+1)
+		if appErr != nil {
+			return "", appErr
+		}
+		if modifier := matches[macroCallRegexp.SubexpIndex("modifier")]; modifier != "" {
+			expanded += " " + modifier
+		}
+		fields[i] = expanded
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// isMacroCall tells whether a field of a query looks like a macro rather than a roll request.
+func isMacroCall(field string) bool {
+	if field == "sum" {
+		return false
+	}
+	if _, err := dice.ParseRequest(field); err == nil {
+		return false
+	}
+	return macroCallRegexp.MatchString(field)
+}
+
+// validateMacroName checks that a macro name cannot be mistaken for a roll request or a subcommand.
+func validateMacroName(name string) *model.AppError {
+	if len(name) > maxMacroNameLength || !macroNameRegexp.MatchString(name) {
+		return appError(fmt.Sprintf("Macro names must start with a letter and only contain lowercase letters, digits and underscores (up to %d characters).", maxMacroNameLength), nil)
+	}
+	if slices.Contains(subcommands, name) || name == "sum" || name == oddsSeparator || !isMacroCall(name) {
+		return appError(fmt.Sprintf("`%s` cannot be used as a macro name, as it already has a meaning for `/roll`.", name), nil)
+	}
+	return nil
+}
+
+// executeMacroCommand handles '/roll macro [channel|team] save|list|delete|show'.
+func (p *Plugin) executeMacroCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	action, rest := splitSubcommand(query)
+	var scope *macroScope
+	switch action {
+	case "channel":
+		scope = channelMacroScope(args.ChannelId)
+	case "team":
+		if args.TeamId == "" {
+			return nil, appError("Team macros can only be used in the channels of a team.", nil)
+		}
+		scope = teamMacroScope(args.TeamId)
+	}
+	if scope != nil {
+		action, rest = splitSubcommand(rest)
+	}
+	name, expression := splitSubcommand(rest)
+	name = strings.ToLower(name)
+
+	switch {
+	case action == "save" && name != "" && expression != "":
+		return p.executeSaveMacroCommand(args, scope, name, expression)
+	case action == "list" && name == "":
+		return p.executeListMacrosCommand(args, scope)
+	case action == "delete" && name != "" && expression == "":
+		return p.executeDeleteMacroCommand(args, scope, name)
+	case action == "show" && name != "" && expression == "":
+		return p.executeShowMacroCommand(args, scope, name)
+	default:
+		return nil, appError("Use `/roll macro [channel|team] save <name> <expression>`, `/roll macro [channel|team] list`, `/roll macro [channel|team] show <name>` or `/roll macro [channel|team] delete <name>`.", nil)
+	}
+}
+
+// getEditableMacroScope returns the scope of the macros edited by a command, checking that
+// the user can edit them: the game masters can edit the channel macros, and the team admins
+// the team macros. Without scope, users edit their own macros.
+func (p *Plugin) getEditableMacroScope(args *model.CommandArgs, scope *macroScope) (*macroScope, *model.AppError) {
+	switch {
+	case scope == nil:
+		return userMacroScope(args.UserId), nil
+	case strings.HasPrefix(scope.key, channelMacrosKeyPrefix):
+		canManage, appErr := p.canManageGame(args.ChannelId, args.UserId)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if !canManage {
+			return nil, appError("Only the game masters and the channel admins can edit the channel macros.", nil)
+		}
+	case !p.API.HasPermissionToTeam(args.UserId, args.TeamId, model.PermissionManageTeam):
+		return nil, appError("Only the team admins can edit the team macros.", nil)
+	}
+	return scope, nil
+}
+
+func (p *Plugin) executeSaveMacroCommand(args *model.CommandArgs, scope *macroScope, name, expression string) (*model.CommandResponse, *model.AppError) {
+	if appErr := validateMacroName(name); appErr != nil {
+		return nil, appErr
+	}
+	scope, appErr := p.getEditableMacroScope(args, scope)
+	if appErr != nil {
+		return nil, appErr
+	}
+	available, appErr := p.getMacros(args.UserId, args.ChannelId, args.TeamId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if _, appErr := kvUpdate(p, scope.key, func(macros *map[string]string) *model.AppError {
+		if *macros == nil {
+			*macros = map[string]string{}
+		}
+		(*macros)[name] = expression
+		// The expression may use other macros, but not the macro itself
+		withMacro := maps.Clone(available)
+		withMacro[name] = expression
+		expanded, appErr := expandMacroQuery(expression, withMacro, 0)
+		if appErr != nil {
+			return appErr
+		}
+		// The variables are those of the user rolling the macro
+		_, appErr = parseExpression(expanded, func(string) (int, bool) { return 0, true })
+		return appErr
+	}); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         fmt.Sprintf("%s `%s` saved: `%s`. Roll it with `/roll %s`.", scope.name, name, expression, name),
+	}, nil
+}
+
+// executeListMacrosCommand lists the macros of a scope, or all the macros available to the user.
+func (p *Plugin) executeListMacrosCommand(args *model.CommandArgs, scope *macroScope) (*model.CommandResponse, *model.AppError) {
+	scopes := []*macroScope{scope}
+	if scope == nil {
+		scopes = getMacroScopes(args.UserId, args.ChannelId, args.TeamId)
+		slices.Reverse(scopes)
+	}
+	sections := []string{}
+	for _, scope := range scopes {
+		macros, appErr := p.getScopeMacros(scope)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if len(macros) > 0 {
+			sections = append(sections, scope.title+":"+formatMacros(macros))
+		}
+	}
+	text := "There are no macros. Save one with `/roll macro save attack 1d20+5`."
+	if len(sections) > 0 {
+		text = strings.Join(sections, "\n\n")
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
+
+// formatMacros lists macros sorted by name, one per line.
+func formatMacros(macros map[string]string) string {
+	text := ""
+	for _, name := range slices.Sorted(maps.Keys(macros)) {
+		text += fmt.Sprintf("\n- `%s`: `%s`", name, macros[name])
+	}
+	return text
+}
+
+func (p *Plugin) executeDeleteMacroCommand(args *model.CommandArgs, scope *macroScope, name string) (*model.CommandResponse, *model.AppError) {
+	scope, appErr := p.getEditableMacroScope(args, scope)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if _, appErr := kvUpdate(p, scope.key, func(macros *map[string]string) *model.AppError {
+		if _, ok := (*macros)[name]; !ok {
+			return appError(fmt.Sprintf("%s no macro named `%s`.", scope.has, name), nil)
+		}
+		delete(*macros, name)
+		return nil
+	}); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         fmt.Sprintf("%s `%s` deleted.", scope.name, name),
+	}, nil
+}
+
+// executeShowMacroCommand shows a macro of a scope, or the macro used by the user for a name.
+func (p *Plugin) executeShowMacroCommand(args *model.CommandArgs, scope *macroScope, name string) (*model.CommandResponse, *model.AppError) {
+	scopes := []*macroScope{scope}
+	if scope == nil {
+		scopes = getMacroScopes(args.UserId, args.ChannelId, args.TeamId)
+		slices.Reverse(scopes)
+	}
+	for _, scope := range scopes {
+		macros, appErr := p.getScopeMacros(scope)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if expression, ok := macros[name]; ok {
+			return &model.CommandResponse{
+				ResponseType: model.CommandResponseTypeEphemeral,
+				Text:         fmt.Sprintf("%s `%s`: `%s`", scope.name, name, expression),
+			}, nil
+		}
+	}
+	if scope == nil {
+		return nil, appError(fmt.Sprintf("There is no macro named `%s`.", name), nil)
+	}
+	return nil, appError(fmt.Sprintf("%s no macro named `%s`.", scope.has, name), nil)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// initTestMacros mocks the macros of userid, in the channel channelid of the team teamid.
+func initTestMacros(api *plugintest.API, userMacros, channelMacros, teamMacros string) {
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", TeamId: "teamid"}, nil)
+	for key, macros := range map[string]string{
+		userMacrosKeyPrefix + "userid":       userMacros,
+		channelMacrosKeyPrefix + "channelid": channelMacros,
+		teamMacrosKeyPrefix + "teamid":       teamMacros,
+	} {
+		if macros == "" {
+			api.On("KVGet", key).Return(nil, nil)
+		} else {
+			api.On("KVGet", key).Return([]byte(macros), nil)
+		}
+	}
+}
+
+func TestExpandMacroQuery(t *testing.T) {
+	macros := map[string]string{
+		"attack": "1d20+7",
+		"damage": "2d6 +4",
+		"full":   "attack damage",
+		"loop":   "1d4 loop",
+	}
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{query: "attack", expected: "1d20+7"},
+		{query: "Attack+2", expected: "1d20+7 +2"},
+		{query: "attack-1 d4", expected: "1d20+7 -1 d4"},
+		{query: "full+1", expected: "1d20+7 2d6 +4 +1"},
+		{query: "attack+@prof-1", expected: "1d20+7 +@prof-1"},
+		{query: "unknown 2d6", expected: "unknown 2d6"},
+	}
+	for _, testCase := range testCases {
+		expanded, appErr := expandMacroQuery(testCase.query, macros, 0)
+		assert.Nil(t, appErr, testCase.query)
+		assert.Equal(t, testCase.expected, expanded, testCase.query)
+	}
+
+	_, appErr := expandMacroQuery("loop", macros, 0)
+	assert.NotNil(t, appErr)
+}
+
+func TestValidateMacroName(t *testing.T) {
+	assert.Nil(t, validateMacroName("attack"))
+	assert.Nil(t, validateMacroName("sneak_attack2"))
+	for _, name := range []string{"d20", "4d6", "12", "odds", "macro", "sum", "vs", "2attack", "fire-ball", "Attack"} {
+		assert.NotNil(t, validateMacroName(name), name)
+	}
+}
+
+func TestRollMacro(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestMacros(api, `{"attack":"3d1+1"}`, `{"attack":"1","wildmagic":"2d1"}`, `{"wildmagic":"1","encounter":"1d1"}`)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll attack+2",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, "**User** rolls `3d1+1 +2` = **8**\n- `3d1+1`: 2 2 2\n- +2", post.Message)
+	assert.Equal(t, "attack+2", getRollData(post).Query)
+
+	// Personal macros override channel macros, which override team macros
+	_, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll wildmagic encounter",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "**User** rolls `2d1 1d1` = **3**\n- `2d1`: 1 1\n- `1d1`: 1", post.Message)
+}
+
+func TestSaveMacro(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestMacros(api, `{"attack":"1d20+7"}`, "", "")
+	api.On("KVCompareAndSet", userMacrosKeyPrefix+"userid", []byte(`{"attack":"1d20+7"}`), mock.Anything).Return(true, nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll macro save Full attack 2d6+4",
+		UserId:    "userid",
+		ChannelId: "channelid",
+		TeamId:    "teamid",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "Macro `full` saved: `attack 2d6+4`. Roll it with `/roll full`.", response.Text)
+	api.AssertCalled(t, "KVCompareAndSet", userMacrosKeyPrefix+"userid", []byte(`{"attack":"1d20+7"}`), []byte(`{"attack":"1d20+7","full":"attack 2d6+4"}`))
+}
+
+func TestSaveMacroErrors(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestMacros(api, `{"attack":"1d20+7"}`, "", "")
+
+	for _, command := range []string{"/roll macro save d20 1d20", "/roll macro save loop loop", "/roll macro save attack", "/roll macro"} {
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: command, UserId: "userid", ChannelId: "channelid", TeamId: "teamid"})
+		assert.NotNil(t, err, command)
+		assert.Nil(t, response, command)
+	}
+
+	// Invalid expressions are explained
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll macro save bad 2d", UserId: "userid", ChannelId: "channelid", TeamId: "teamid"})
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "'2d' is not a valid die code")
+	api.AssertNotCalled(t, "KVCompareAndSet", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSaveChannelMacro(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestGM(api)
+	initTestMacros(api, `{"attack":"1d20+7"}`, "", "")
+	api.On("KVCompareAndSet", channelMacrosKeyPrefix+"channelid", []byte(nil), []byte(`{"wildmagic":"1d100"}`)).Return(true, nil)
+	api.On("KVGet", userMacrosKeyPrefix+"gmid").Return(nil, nil)
+
+	args := &model.CommandArgs{Command: "/roll macro channel save wildmagic 1d100", UserId: "userid", ChannelId: "channelid", TeamId: "teamid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+	api.AssertNotCalled(t, "KVCompareAndSet", mock.Anything, mock.Anything, mock.Anything)
+
+	args.UserId = "gmid"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "Channel macro `wildmagic` saved: `1d100`. Roll it with `/roll wildmagic`.", response.Text)
+}
+
+func TestSaveTeamMacro(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestMacros(api, "", "", `{"encounter":"1d12"}`)
+	api.On("HasPermissionToTeam", "userid", "teamid", model.PermissionManageTeam).Return(false)
+	api.On("HasPermissionToTeam", "adminid", "teamid", model.PermissionManageTeam).Return(true)
+	api.On("KVGet", userMacrosKeyPrefix+"adminid").Return(nil, nil)
+	api.On("KVCompareAndSet", teamMacrosKeyPrefix+"teamid", []byte(`{"encounter":"1d12"}`), []byte(`{}`)).Return(true, nil)
+
+	args := &model.CommandArgs{Command: "/roll macro team delete encounter", UserId: "userid", ChannelId: "channelid", TeamId: "teamid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+
+	args.UserId = "adminid"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "Team macro `encounter` deleted.", response.Text)
+}
+
+func TestListMacros(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestMacros(api, `{"damage":"2d6+4","attack":"1d20+7"}`, "", `{"encounter":"1d12"}`)
+	api.On("KVGet", userMacrosKeyPrefix+"otherid").Return(nil, nil)
+	api.On("KVGet", teamMacrosKeyPrefix+"otherteamid").Return(nil, nil)
+
+	args := &model.CommandArgs{Command: "/roll macro list", UserId: "userid", ChannelId: "channelid", TeamId: "teamid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "Your macros:\n- `attack`: `1d20+7`\n- `damage`: `2d6+4`\n\nTeam macros:\n- `encounter`: `1d12`", response.Text)
+
+	args.Command = "/roll macro team list"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "Team macros:\n- `encounter`: `1d12`", response.Text)
+
+	args.Command = "/roll macro show encounter"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "Team macro `encounter`: `1d12`", response.Text)
+
+	args.Command = "/roll macro channel show encounter"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.NotNil(t, err)
+	assert.Equal(t, "This channel has no macro named `encounter`.", err.Message)
+
+	response, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll macro list", UserId: "otherid", ChannelId: "channelid", TeamId: "otherteamid"})
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "There are no macros.")
+}
+
+func TestDeleteMacro(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestMacros(api, `{"attack":"1d20+7"}`, "", "")
+	api.On("KVCompareAndSet", userMacrosKeyPrefix+"userid", []byte(`{"attack":"1d20+7"}`), []byte(`{}`)).Return(true, nil)
+
+	args := &model.CommandArgs{Command: "/roll macro delete attack", UserId: "userid", ChannelId: "channelid", TeamId: "teamid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "Macro `attack` deleted.", response.Text)
+
+	args.Command = "/roll macro delete damage"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.NotNil(t, err)
+	assert.Equal(t, "You have no macro named `damage`.", err.Message)
+	assert.Nil(t, response)
+}
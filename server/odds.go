@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+// oddsSeparator separates the expressions to compare in '/roll odds 2d6 vs 1d12'.
+const oddsSeparator string = "vs"
+
+// rollStatisticsFooter describes how good a total is compared to the possible results of an expression.
+// The percentile is omitted when the distribution is too expensive to compute.
+func rollStatisticsFooter(expression *dice.Expression, total int) string {
+	minimum, maximum, mean := expression.Bounds()
+	footer := fmt.Sprintf("\n*min %d · max %d · mean %.2f", minimum, maximum, mean)
+	if dist, err := expression.Distribution(); err == nil {
+		footer += fmt.Sprintf(" · percentile %.0f", 100*dist.Cumulative(total))
+	}
+	return footer + "*"
+}
+
+// executeOddsCommand sends the user an ephemeral post describing the distribution of one
+// or several expressions, and a direct message with a chart comparing them.
+func (p *Plugin) executeOddsCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	expressions := splitOddsQuery(query)
+	if len(expressions) == 0 {
+		return nil, appError("No expression found. Try for example `/roll odds 2d6 vs 1d12`.", nil)
+	}
+	if len(expressions) > len(chartPalette) {
+		return nil, appError(fmt.Sprintf("Too many expressions to compare; maximum is %d.", len(chartPalette)), nil)
+	}
+
+	distributions := make([]*dice.Distribution, len(expressions))
+	lines := make([]string, len(expressions))
+	for i, text := range expressions {
+		expression, appErr := p.parseUserExpression(args.UserId, args.ChannelId, text)
+		if appErr != nil {
+			return nil, appErr
+		}
+		dist, err := expression.Distribution()
+		if err != nil {
+			return nil, appError(err.Error(), err)
+		}
+		distributions[i] = dist
+		lines[i] = fmt.Sprintf("%s %s: %s", chartLegends[i], dice.Code(text), dist.Summary())
+	}
+
+	chart, err := drawDistributionChart(distributions)
+	if err != nil {
+		return nil, appError("Could not draw the odds chart.", err)
+	}
+	// Ephemeral posts are not saved, so the chart is attached to a direct message from the bot
+	dm, appErr := p.API.GetDirectChannel(p.diceBotID, args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	fileInfo, appErr := p.API.UploadFile(chart, dm.Id, "odds.png")
+	if appErr != nil {
+		return nil, appErr
+	}
+	text := "**Odds**\n- " + strings.Join(lines, "\n- ")
+	if _, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: dm.Id,
+		Message:   text,
+		FileIds:   []string{fileInfo.Id},
+	}); appErr != nil {
+		return nil, appErr
+	}
+
+	if args.ChannelId != dm.Id {
+		p.API.SendEphemeralPost(args.UserId, &model.Post{
+			UserId:    p.diceBotID,
+			ChannelId: args.ChannelId,
+			RootId:    args.RootId,
+			Message:   text + "\n\nThe chart is in your direct messages with the dice bot.",
+		})
+	}
+	return &model.CommandResponse{}, nil
+}
+
+// splitOddsQuery splits '2d6 +1 vs 1d12' into the expressions '2d6 +1' and '1d12'.
+func splitOddsQuery(query string) []string {
+	expressions := []string{}
+	current := []string{}
+	for _, field := range append(strings.Fields(query), oddsSeparator) {
+		if field != oddsSeparator {
+			current = append(current, field)
+			continue
+		}
+		if len(current) > 0 {
+			expressions = append(expressions, strings.Join(current, " "))
+		}
+		current = []string{}
+	}
+	return expressions
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+func TestSplitOddsQuery(t *testing.T) {
+	assert.Equal(t, []string{"2d6 +1", "1d12"}, splitOddsQuery("2d6 +1 vs 1d12"))
+	assert.Equal(t, []string{"d20"}, splitOddsQuery(" vs d20 vs "))
+	assert.Empty(t, splitOddsQuery(""))
+}
+
+func TestDrawDistributionChart(t *testing.T) {
+	for _, query := range []string{"1", "2d6", "100d100"} {
+		expression, err := dice.Parse(query)
+		assert.Nil(t, err)
+		dist, err := expression.Distribution()
+		assert.Nil(t, err)
+		chart, err := drawDistributionChart([]*dice.Distribution{dist, dice.DieDistribution(12, 0)})
+		assert.Nil(t, err, query)
+		img, err := png.Decode(bytes.NewReader(chart))
+		assert.Nil(t, err, query)
+		assert.Equal(t, chartWidth, img.Bounds().Dx(), query)
+		assert.Equal(t, chartHeight, img.Bounds().Dy(), query)
+	}
+}
+
+func TestOddsCommand(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	api.On("GetDirectChannel", "botid", "userid").Return(&model.Channel{Id: "dmid"}, nil)
+	api.On("UploadFile", mock.Anything, "dmid", "odds.png").Return(&model.FileInfo{Id: "fileid"}, nil)
+	var dmPost *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		dmPost = created
+		return created, nil
+	})
+	var post *model.Post
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		post = args.Get(1).(*model.Post)
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll odds 2d6 vs 1d12",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	odds := "**Odds**\n" +
+		"- 🟦 `2d6`: from 2 to 12, average 7.00, most likely 7 (16.67%)\n" +
+		"- 🟥 `1d12`: from 1 to 12, average 6.50, most likely 1 (8.33%)"
+	assert.NotNil(t, dmPost)
+	assert.Equal(t, "dmid", dmPost.ChannelId)
+	assert.Equal(t, "botid", dmPost.UserId)
+	assert.Equal(t, []string{"fileid"}, []string(dmPost.FileIds))
+	assert.Equal(t, odds, dmPost.Message)
+	assert.NotNil(t, post)
+	assert.Empty(t, post.FileIds)
+	assert.Equal(t, odds+"\n\nThe chart is in your direct messages with the dice bot.", post.Message)
+
+	// In the direct message with the bot, the chart is enough
+	post = nil
+	_, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll odds 2d6", UserId: "userid", ChannelId: "dmid"})
+	assert.Nil(t, err)
+	assert.Nil(t, post)
+}
+
+func TestOddsCommandBadInputs(t *testing.T) {
+	p, _ := initTestPlugin()
+	for _, command := range []string{"/roll odds", "/roll odds vs", "/roll odds 100d1000", "/roll odds 1 vs 2 vs 3 vs 4 vs 5"} {
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: command, UserId: "userid"})
+		assert.NotNil(t, err, command)
+		assert.Nil(t, response, command)
+	}
+}
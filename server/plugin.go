@@ -1,13 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
 )
 
 const (
@@ -27,17 +32,53 @@ type Plugin struct {
 
 	// BotId of the created bot account for dice rolling
 	diceBotID string
+
+	// router dispatches the HTTP requests received by ServeHTTP
+	router *http.ServeMux
+
+	// stopSealedRollsWatcher stops the background reveal of expired sealed rolls
+	stopSealedRollsWatcher chan struct{}
+
+	// webhooks tracks the requests being sent to the webhooks, whose retries stop when
+	// stopWebhooks is closed
+	webhooks     sync.WaitGroup
+	stopWebhooks chan struct{}
+
+	// botDirectChannels caches whether the channels of the posted messages are direct messages
+	// with the dice bot, by channel ID, as the type and the members of a channel never change
+	botDirectChannels sync.Map
 }
 
 func (p *Plugin) OnActivate() error {
-	return p.API.RegisterCommand(&model.Command{
+	p.router = p.initRouter()
+	p.stopWebhooks = make(chan struct{})
+
+	if err := p.API.RegisterCommand(&model.Command{
 		Trigger:          trigger,
 		Description:      "Roll one or more dice",
 		DisplayName:      "Dice roller ⚄",
 		AutoComplete:     true,
 		AutoCompleteDesc: "Roll one or several dice. ⚁ ⚄ Try /roll help for a list of possibilities.",
 		AutoCompleteHint: "20 d6+4 3d4 [sum]",
-	})
+	}); err != nil {
+		return err
+	}
+
+	// Started last, as OnDeactivate is not called when the activation fails
+	p.stopSealedRollsWatcher = make(chan struct{})
+	go p.watchSealedRolls(p.stopSealedRollsWatcher)
+	return nil
+}
+
+func (p *Plugin) OnDeactivate() error {
+	if p.stopSealedRollsWatcher != nil {
+		close(p.stopSealedRollsWatcher)
+	}
+	if p.stopWebhooks != nil {
+		close(p.stopWebhooks)
+	}
+	p.waitForWebhooks(webhookShutdownTimeout)
+	return nil
 }
 
 func (p *Plugin) GetHelpMessage() *model.CommandResponse {
@@ -53,6 +94,21 @@ func (p *Plugin) GetHelpMessage() *model.CommandResponse {
 			"- `/roll 5D6+3` to roll five 6-sided dice and add 3 the result of each die.\n" +
 			"- `/roll 5D6 +3` (with a space) to roll five 6-sided dice and add 3 the total.\n" +
 			"- `/roll 5 d8 13D20` to roll different dice at the same time.\n" +
+			"- `/roll odds 2d6 vs 1d12` to compare the odds of different rolls.\n" +
+			"- `/roll gm 1d20` to make a secret roll, only shown to you and the game masters of the channel.\n" +
+			"- `/roll gm set @alice @bob` to choose the game masters of the channel (channel admins only), `/roll gm list` to list them.\n" +
+			"- `/roll blind 1d20` to make a blind roll, only shown to the game masters until they reveal it.\n" +
+			"- `/roll sealed 1d20` to make a roll hidden to everyone until a game master uses `/roll reveal`.\n" +
+			"- `/roll private 1d20` to roll for yourself only.\n" +
+			"- `/roll whisper @bob @carol 1d20` to share a roll with some users only.\n" +
+			"- `/roll macro save attack 1d20+5` to save a macro, then `/roll attack` or `/roll attack+2` to roll it. `/roll macro list`, `/roll macro show attack` and `/roll macro delete attack` manage your macros.\n" +
+			"- `/roll macro channel save wildmagic 1d100` to share a macro with the channel (game masters only), or `/roll macro team save ...` with the team (team admins only). Your macros take precedence over the channel macros, and the channel macros over the team macros.\n" +
+			"- `/roll set dex 3` to set a variable used as `/roll 1d20+@dex`, `/roll set --channel dex 4` to set it in the current channel only. `/roll vars` lists your variables and `/roll unset dex` removes one.\n" +
+			"- `/roll sheet import` to import the 5e character sheet you posted as a JSON file, then `/roll check stealth` or `/roll save wis` to roll with the modifiers of the sheet.\n" +
+			"- `/roll character list` to list your characters, `/roll character use Aria` to choose the one you play in this channel, and `/roll as Goblin 1d20+4` to roll as another of your characters.\n" +
+			"- `/roll attack +7 2d6 +4 ac 15` to roll to hit with a d20, then the damage if the attack hits, with the damage dice doubled on a natural 20. The armor class is optional, and `2d6 +4` adds 4 once while `2d6+4` adds 4 to each die.\n" +
+			"- `/roll inline on` to roll the expressions written between double brackets in the messages of the channel, such as `[[1d20+5]]` (game masters and channel admins only, when enabled in the plugin settings).\n" +
+			"- Mention @dicerollerbot with an expression, such as `@dicerollerbot 1d20+5`, or send it to the bot in a direct message, to roll without the command.\n" +
 			"- `/roll help` will show this help text.\n\n" +
 			" ⚅ ⚂ Let's get rolling! ⚁ ⚄",
 		Props: props,
@@ -73,93 +129,280 @@ func (p *Plugin) ExecuteCommand(_ *plugin.Context, args *model.CommandArgs) (*mo
 			return p.GetHelpMessage(), nil
 		}
 
-		post, generatePostError := p.generateDicePost(query, args.UserId, args.ChannelId, args.RootId)
-		if generatePostError != nil {
-			return nil, generatePostError
+		response, appErr := p.executeRollCommand(args, query)
+		var parseErr *dice.ParseError
+		if appErr != nil && errors.As(appErr, &parseErr) {
+			return parseErrorResponse(query, parseErr), nil
+		}
+		return response, appErr
+	}
+
+	return nil, appError("Expected trigger "+cmd+" but got "+args.Command, nil)
+}
+
+// executeRollCommand dispatches the query of the /roll command to its subcommand,
+// or rolls it when it has none.
+func (p *Plugin) executeRollCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	switch subcommand, subquery := splitSubcommand(query); subcommand {
+	case "odds":
+		return p.executeOddsCommand(args, subquery)
+	case "gm":
+		return p.executeGMCommand(args, subquery)
+	case "secret":
+		return p.executeSecretRollCommand(args, subquery)
+	case "blind":
+		return p.executeBlindRollCommand(args, subquery)
+	case "private":
+		return p.executePrivateRollCommand(args, subquery)
+	case "whisper":
+		return p.executeWhisperRollCommand(args, subquery)
+	case "sealed":
+		return p.executeSealedRollCommand(args, subquery)
+	case "reveal":
+		return p.executeRevealCommand(args)
+	case "macro":
+		return p.executeMacroCommand(args, subquery)
+	case "set":
+		return p.executeSetVariableCommand(args, subquery)
+	case "unset":
+		return p.executeUnsetVariableCommand(args, subquery)
+	case "vars":
+		return p.executeListVariablesCommand(args)
+	case "sheet":
+		return p.executeSheetCommand(args, subquery)
+	case "check":
+		return p.executeCheckCommand(args, subquery)
+	case "save":
+		return p.executeSaveCommand(args, subquery)
+	case "character":
+		return p.executeCharacterCommand(args, subquery)
+	case "as":
+		return p.executeAsCommand(args, subquery)
+	case "inline":
+		return p.executeInlineCommand(args, subquery)
+	case attackMacroName:
+		isAttack, appErr := p.isAttackCommand(args, subquery)
+		if appErr != nil {
+			return nil, appErr
 		}
-		_, createPostError := p.API.CreatePost(post)
-		if createPostError != nil {
-			return nil, createPostError
+		if isAttack {
+			return p.executeAttackCommand(args, subquery)
 		}
+	}
 
-		return &model.CommandResponse{}, nil
+	post, generatePostError := p.generateDicePost(query, args.UserId, args.ChannelId, args.RootId)
+	if generatePostError != nil {
+		return nil, generatePostError
+	}
+	_, createPostError := p.createDicePost(post)
+	if createPostError != nil {
+		return nil, createPostError
 	}
 
-	return nil, appError("Expected trigger "+cmd+" but got "+args.Command, nil)
+	return &model.CommandResponse{}, nil
 }
 
 func (p *Plugin) generateDicePost(query, userID, channelID, rootID string) (*model.Post, *model.AppError) {
-	// Get the user to display their name
-	user, userErr := p.API.GetUser(userID)
-	if userErr != nil {
-		return nil, userErr
+	return p.generateCharacterDicePost("", query, userID, channelID, rootID)
+}
+
+// generateCharacterDicePost rolls a query for a user as one of their characters, showing the
+// name of the character in the headline. Without a character, the name of the user is shown.
+func (p *Plugin) generateCharacterDicePost(character, query, userID, channelID, rootID string) (*model.Post, *model.AppError) {
+	displayName, appErr := p.getRollerName(character, userID)
+	if appErr != nil {
+		return nil, appErr
 	}
-	displayName := user.Nickname
-	if displayName == "" {
-		displayName = user.Username
-	}
-
-	text := fmt.Sprintf("**%s** rolls *%s* = ", displayName, query)
-	sum := 0
-	rollRequests := strings.Fields(query)
-	if len(rollRequests) == 0 || query == "sum" {
-		return nil, appError("No roll request arguments found (such as '20', '4d6', etc.).", nil)
-	}
-	singleResultCount := 0
-	numericDiceCount := 0
-	formattedRollDetails := make([]string, len(rollRequests))
-	for i, rollRequest := range rollRequests {
-		// Ignore the 'sum' keyword, remnant of a previous version
-		// kept for the compatibility
-		if rollRequest == "sum" {
-			continue
-		}
-		result, err := rollDice(rollRequest)
-		if err != nil {
-			return nil, appError(fmt.Sprintf("%s See `/roll help` for examples.", err.Error()), err)
-		}
-		if result.rollType == numeric {
-			numericDiceCount++
-			rollDetails := fmt.Sprintf("%s: ", rollRequest)
-			singleResultCount += len(result.results)
-			for _, roll := range result.results {
-				rollDetails += fmt.Sprintf("%d ", roll)
-				sum += roll
-			}
-			formattedRollDetails[i] = strings.TrimSpace(rollDetails)
-		} else {
-			formattedRollDetails[i] = fmt.Sprintf("%+d", result.sumModifier)
-			sum += result.sumModifier
-		}
+	expression, appErr := p.parseUserExpression(userID, channelID, query)
+	if appErr != nil {
+		return nil, appErr
 	}
+	result := roller.Roll(expression)
 
-	// Always display the total
-	text += fmt.Sprintf("**%d**", sum)
+	text := result.Breakdown(displayName)
+	if p.getConfiguration().ShowRollStatistics {
+		text += rollStatisticsFooter(expression, result.Total)
+	}
 
-	// Display roll details only of necessary
-	if singleResultCount > 1 {
-		formattedRollDetails = filterEmptyString(formattedRollDetails)
-		text += fmt.Sprintf("\n- %s", strings.Join(formattedRollDetails, "\n- "))
+	post := &model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: channelID,
+		RootId:    rootID,
+		Message:   text,
+	}
+	setRollData(post, &rollData{
+		Version:    rollDataVersion,
+		Query:      query,
+		Expression: expression.Text,
+		UserID:     userID,
+		Character:  character,
+		Total:      result.Total,
+		Requests:   result.Data(),
+	})
+	attachRollActions(post)
+	return post, nil
+}
+
+// createDicePost publishes a public dice post, and notifies the integrations of the roll.
+func (p *Plugin) createDicePost(post *model.Post) (*model.Post, *model.AppError) {
+	createdPost, appErr := p.API.CreatePost(post)
+	if appErr != nil {
+		return nil, appErr
 	}
+	p.sendRollWebhooks(createdPost)
+	p.publishRollEvent(createdPost)
+	return createdPost, nil
+}
 
-	return &model.Post{
+// generateAdvantagePost rolls the query twice and keeps the highest total, as a character of
+// the user if any.
+func (p *Plugin) generateAdvantagePost(character, query, userID, channelID, rootID string) (*model.Post, *model.AppError) {
+	displayName, userErr := p.getRollerName(character, userID)
+	if userErr != nil {
+		return nil, userErr
+	}
+
+	expression, appErr := p.parseUserExpression(userID, channelID, query)
+	if appErr != nil {
+		return nil, appErr
+	}
+	kept, dropped := roller.Roll(expression), roller.Roll(expression)
+	if dropped.Total > kept.Total {
+		kept, dropped = dropped, kept
+	}
+
+	text := fmt.Sprintf("**%s** rolls %s with advantage = **%d**", displayName, dice.Code(query), kept.Total)
+	text += fmt.Sprintf("\n- kept: **%d** (%s)", kept.Total, strings.Join(kept.Details(), ", "))
+	text += fmt.Sprintf("\n- dropped: ~~%d~~ (%s)", dropped.Total, strings.Join(dropped.Details(), ", "))
+
+	post := &model.Post{
 		UserId:    p.diceBotID,
 		ChannelId: channelID,
 		RootId:    rootID,
 		Message:   text,
-	}, nil
+	}
+	setRollData(post, &rollData{
+		Version:    rollDataVersion,
+		Query:      query,
+		Expression: expression.Text,
+		UserID:     userID,
+		Character:  character,
+		Advantage:  true,
+		Total:      kept.Total,
+		Requests:   kept.Data(),
+		Dropped:    dropRequests(dropped.Data()),
+	})
+	attachRollActions(post)
+	return post, nil
+}
+
+// roller rolls the dice of the plugin.
+var roller dice.Roller
+
+// parseUserExpression parses a roll query of a user, expanding their macros and resolving
+// their variables.
+func (p *Plugin) parseUserExpression(userID, channelID, query string) (*dice.Expression, *model.AppError) {
+	expanded, appErr := p.expandMacros(userID, channelID, query)
+	if appErr != nil {
+		return nil, appErr
+	}
+	variables, appErr := p.getVariableResolver(userID, channelID, expanded)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return parseExpression(expanded, variables)
+}
+
+// parseExpression parses a roll query, turning the errors into user-facing messages.
+func parseExpression(query string, variables dice.VariableResolver) (*dice.Expression, *model.AppError) {
+	expression, err := dice.ParseWithVariables(query, variables)
+	if errors.Is(err, dice.ErrNoRequest) {
+		return nil, appError("No roll request arguments found (such as '20', '4d6', etc.).", err)
+	}
+	if err != nil {
+		// Wrap the error so that the command can locate it in the expression
+		return nil, appError(fmt.Sprintf("%s See `/roll help` for examples.", err.Error()), nil).Wrap(err)
+	}
+	return expression, nil
+}
+
+func rollQuery(query string) (*dice.Result, *model.AppError) {
+	expression, appErr := parseExpression(query, nil)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return roller.Roll(expression), nil
 }
 
-func filterEmptyString(arr []string) []string {
-	result := []string{}
-	for _, val := range arr {
-		if val != "" {
-			result = append(result, val)
+// getDisplayName returns the nickname of the user, or their username if they have none.
+func (p *Plugin) getDisplayName(userID string) (string, *model.AppError) {
+	user, userErr := p.API.GetUser(userID)
+	if userErr != nil {
+		return "", userErr
+	}
+	if user.Nickname != "" {
+		return user.Nickname, nil
+	}
+	return user.Username, nil
+}
+
+// getRollerName returns the name shown in the headline of a roll: the name of the character
+// the user rolls as, if any, or the display name of the user.
+func (p *Plugin) getRollerName(character, userID string) (string, *model.AppError) {
+	if character != "" {
+		return character, nil
+	}
+	return p.getDisplayName(userID)
+}
+
+// readMentions resolves the users mentioned at the start of a list of fields,
+// and returns them along with the remaining fields.
+func (p *Plugin) readMentions(fields []string) ([]*model.User, []string, *model.AppError) {
+	users := []*model.User{}
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		username := strings.TrimPrefix(fields[0], "@")
+		user, appErr := p.API.GetUserByUsername(username)
+		if appErr != nil {
+			return nil, nil, appError(fmt.Sprintf("Could not find the user @%s.", username), appErr)
+		}
+		if !slices.ContainsFunc(users, func(u *model.User) bool { return u.Id == user.Id }) {
+			users = append(users, user)
 		}
+		fields = fields[1:]
 	}
-	return result
+	return users, fields, nil
 }
 
+// formatUsernames formats users as '@alice, @bob'.
+func formatUsernames(users []*model.User) string {
+	usernames := make([]string, len(users))
+	for i, user := range users {
+		usernames[i] = "@" + user.Username
+	}
+	return strings.Join(usernames, ", ")
+}
+
+// threadRootID returns the ID of the thread a post belongs to, or starts.
+func threadRootID(post *model.Post) string {
+	if post.RootId != "" {
+		return post.RootId
+	}
+	return post.Id
+}
+
+// splitSubcommand separates the first word of a query from the rest.
+func splitSubcommand(query string) (string, string) {
+	subcommand, subquery, _ := strings.Cut(query, " ")
+	return subcommand, strings.TrimSpace(subquery)
+}
+
+// plainTextRegexp matches the texts chosen by users or integrations, such as character names,
+// that the bot can show as they are: they can neither mention anyone nor use Markdown.
+var plainTextRegexp = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} '.,:;!?()+/-]*$`)
+
+// plainTextRule describes plainTextRegexp in the error messages.
+const plainTextRule = "letters, digits, spaces and simple punctuation such as `'.,:;!?()+-/`"
+
 func appError(message string, err error) *model.AppError {
 	errorMessage := ""
 	if err != nil {
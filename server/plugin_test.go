@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -35,10 +36,6 @@ func TestBadInputs(t *testing.T) {
 	testCases := []string{
 		"/lolzies d20",
 		"/roll ",
-		"/roll d0",
-		"/roll hahaha",
-		"/roll 6d",
-		"/roll 0d5",
 	}
 	for _, testCase := range testCases {
 		// Wrong dice requests
@@ -52,11 +49,32 @@ func TestBadInputs(t *testing.T) {
 	}
 }
 
+func TestBadExpressions(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	initTestMacros(api, "", "", "")
+
+	// Invalid expressions are explained to the user only, instead of failing the command
+	testCases := map[string]string{
+		"/roll d0":     "'d0' is not a valid die code: expected a number of sides.\n```\nd0\n ^\n```\nSee `/roll help` for examples.",
+		"/roll 6d":     "'6d' is not a valid die code: expected a number of sides.\n```\n6d\n  ^\n```\nSee `/roll help` for examples.",
+		"/roll 0d5":    "'0d5' is not a valid die code: expected a number of dice or 'd' or a modifier such as '+1'.\n```\n0d5\n^\n```\nDid you mean `/roll d5`?",
+		"/roll hahaha": "'hahaha' is not a valid die code: expected a number of dice or 'd' or a modifier such as '+1'.\n```\nhahaha\n^\n```\nSee `/roll help` for examples.",
+	}
+	for command, expected := range testCases {
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: command, UserId: "userid", ChannelId: "channelid"})
+		assert.Nil(t, err, command)
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType, command)
+		assert.Equal(t, expected, response.Text, command)
+	}
+}
+
 func TestGoodInputs(t *testing.T) {
 	p, api := initTestPlugin()
 	var post *model.Post
-	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
-		post = args.Get(0).(*model.Post)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
 	})
 	assert.Nil(t, p.OnActivate())
 
@@ -64,13 +82,13 @@ func TestGoodInputs(t *testing.T) {
 		inputDiceRequest string
 		expectedText     string
 	}{
-		{inputDiceRequest: "3d1 sum", expectedText: "**User** rolls *3d1 sum* = **3**\n- 3d1: 1 1 1"},
-		{inputDiceRequest: "5d1", expectedText: "**User** rolls *5d1* = **5**\n- 5d1: 1 1 1 1 1"},
-		{inputDiceRequest: "1", expectedText: "**User** rolls *1* = **1**"},
-		{inputDiceRequest: "+42", expectedText: "**User** rolls *+42* = **42**"},
-		{inputDiceRequest: "4d1+3", expectedText: "**User** rolls *4d1+3* = **16**\n- 4d1+3: 4 4 4 4"},
-		{inputDiceRequest: "4d1 +3", expectedText: "**User** rolls *4d1 +3* = **7**\n- 4d1: 1 1 1 1\n- +3"},
-		{inputDiceRequest: "4d1 2d1 +42", expectedText: "**User** rolls *4d1 2d1 +42* = **48**\n- 4d1: 1 1 1 1\n- 2d1: 1 1\n- +42"},
+		{inputDiceRequest: "3d1 sum", expectedText: "**User** rolls `3d1 sum` = **3**\n- `3d1`: 1 1 1"},
+		{inputDiceRequest: "5d1", expectedText: "**User** rolls `5d1` = **5**\n- `5d1`: 1 1 1 1 1"},
+		{inputDiceRequest: "1", expectedText: "**User** rolls `1` = **1**"},
+		{inputDiceRequest: "+42", expectedText: "**User** rolls `+42` = **42**"},
+		{inputDiceRequest: "4d1+3", expectedText: "**User** rolls `4d1+3` = **16**\n- `4d1+3`: 4 4 4 4"},
+		{inputDiceRequest: "4d1 +3", expectedText: "**User** rolls `4d1 +3` = **7**\n- `4d1`: 1 1 1 1\n- +3"},
+		{inputDiceRequest: "4d1 2d1 +42", expectedText: "**User** rolls `4d1 2d1 +42` = **48**\n- `4d1`: 1 1 1 1\n- `2d1`: 1 1\n- +42"},
 	}
 	for _, testCase := range testCases {
 		command := &model.CommandArgs{
@@ -87,12 +105,44 @@ func TestGoodInputs(t *testing.T) {
 	}
 }
 
+func TestRollStatisticsFooter(t *testing.T) {
+	p, api := initTestPlugin()
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+	p.setConfiguration(&configuration{ShowRollStatistics: true})
+
+	testCases := []struct {
+		inputDiceRequest string
+		expectedText     string
+	}{
+		{inputDiceRequest: "5d1", expectedText: "**User** rolls `5d1` = **5**\n- `5d1`: 1 1 1 1 1\n*min 5 · max 5 · mean 5.00 · percentile 100*"},
+		{inputDiceRequest: "2d1 +3", expectedText: "**User** rolls `2d1 +3` = **5**\n- `2d1`: 1 1\n- +3\n*min 5 · max 5 · mean 5.00 · percentile 100*"},
+		{inputDiceRequest: "100d1000", expectedText: "*min 100 · max 100000 · mean 50050.00*"},
+	}
+	for _, testCase := range testCases {
+		command := &model.CommandArgs{
+			Command: "/roll " + testCase.inputDiceRequest,
+			UserId:  "userid",
+		}
+		_, err := p.ExecuteCommand(&plugin.Context{}, command)
+		testLabel := "Testing " + testCase.inputDiceRequest
+		assert.Nil(t, err, testLabel)
+		assert.NotNil(t, post, testLabel)
+		assert.True(t, strings.HasSuffix(post.Message, testCase.expectedText), testLabel)
+	}
+}
+
 func initTestPlugin() (*Plugin, *plugintest.API) {
 	api := &plugintest.API{}
 	api.On("RegisterCommand", mock.Anything).Return(nil)
 	api.On("UnregisterCommand", mock.Anything, mock.Anything).Return(nil)
+	api.On("PublishWebSocketEvent", rollResultEvent, mock.Anything, mock.Anything).Return()
 	api.On("GetUser", mock.Anything).Return(&model.User{
 		Id:       "userid",
+		Username: "user",
 		Nickname: "User",
 	}, (*model.AppError)(nil))
 
@@ -101,3 +151,13 @@ func initTestPlugin() (*Plugin, *plugintest.API) {
 
 	return &p, api
 }
+
+func TestActivateFailure(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	api.On("RegisterCommand", mock.Anything).Return(errors.New("registration failed"))
+	p.SetAPI(api)
+
+	assert.NotNil(t, p.OnActivate())
+	assert.Nil(t, p.stopSealedRollsWatcher)
+}
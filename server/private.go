@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// maxWhisperRecipients is the maximum number of users a roll can be whispered to,
+// as group messages are limited to 8 members including the roller and the dice bot.
+const maxWhisperRecipients int = 6
+
+// executePrivateRollCommand shows the roll to the roller only.
+func (p *Plugin) executePrivateRollCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	post, appErr := p.generateDicePost(query, args.UserId, args.ChannelId, args.RootId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	// Rolling again from an ephemeral post would not be private anymore
+	post.DelProp(model.PostPropsAttachments)
+	p.API.SendEphemeralPost(args.UserId, post)
+
+	return &model.CommandResponse{}, nil
+}
+
+// executeWhisperRollCommand posts the roll in a group message between the dice bot,
+// the roller and the users mentioned at the start of the query.
+func (p *Plugin) executeWhisperRollCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	users, fields, appErr := p.readMentions(strings.Fields(query))
+	if appErr != nil {
+		return nil, appErr
+	}
+	memberIDs := []string{p.diceBotID, args.UserId}
+	recipients := []*model.User{}
+	for _, user := range users {
+		if !slices.Contains(memberIDs, user.Id) {
+			memberIDs = append(memberIDs, user.Id)
+			recipients = append(recipients, user)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, appError("Mention the users to whisper the roll to, for example `/roll whisper @bob 1d20`.", nil)
+	}
+	if len(recipients) > maxWhisperRecipients {
+		return nil, appError(fmt.Sprintf("A roll can be whispered to %d users at most.", maxWhisperRecipients), nil)
+	}
+
+	groupChannel, appErr := p.API.GetGroupChannel(memberIDs)
+	if appErr != nil {
+		return nil, appErr
+	}
+	post, appErr := p.generateDicePost(strings.Join(fields, " "), args.UserId, groupChannel.Id, "")
+	if appErr != nil {
+		return nil, appErr
+	}
+	if _, appErr = p.API.CreatePost(post); appErr != nil {
+		return nil, appErr
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         fmt.Sprintf("Roll whispered to %s.", formatUsernames(recipients)),
+	}, nil
+}
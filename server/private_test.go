@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivateRoll(t *testing.T) {
+	p, api := initTestPlugin()
+	var post *model.Post
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		post = args.Get(1).(*model.Post)
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll private 2d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.NotNil(t, post)
+	assert.Equal(t, "**User** rolls `2d1` = **2**\n- `2d1`: 1 1", post.Message)
+	assert.Equal(t, "channelid", post.ChannelId)
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+}
+
+func TestWhisperRoll(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	api.On("GetUserByUsername", "bob").Return(&model.User{Id: "bobid", Username: "bob"}, nil)
+	api.On("GetUserByUsername", "carol").Return(&model.User{Id: "carolid", Username: "carol"}, nil)
+	api.On("GetGroupChannel", []string{"botid", "userid", "bobid", "carolid"}).Return(&model.Channel{Id: "groupid"}, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
+		post = args.Get(0).(*model.Post)
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll whisper @bob @carol @bob 2d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+		RootId:    "rootid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, "Roll whispered to @bob, @carol.", response.Text)
+	assert.NotNil(t, post)
+	assert.Equal(t, "groupid", post.ChannelId)
+	assert.Equal(t, "", post.RootId)
+	assert.Equal(t, "**User** rolls `2d1` = **2**\n- `2d1`: 1 1", post.Message)
+}
+
+func TestWhisperRollBadInputs(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("GetUserByUsername", "nobody").Return(nil, &model.AppError{})
+
+	for _, command := range []string{"/roll whisper 1d20", "/roll whisper @nobody 1d20"} {
+		response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: command, UserId: "userid"})
+		assert.NotNil(t, err, command)
+		assert.Nil(t, response, command)
+	}
+}
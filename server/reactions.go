@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+// rerollEmoji is the name of the 🎲 emoji, which rolls again a dice post when its roller adds it.
+const rerollEmoji = "game_die"
+
+// ReactionHasBeenAdded rolls again the query of a dice post when its roller reacts with 🎲,
+// and posts the result in the same thread.
+func (p *Plugin) ReactionHasBeenAdded(_ *plugin.Context, reaction *model.Reaction) {
+	if reaction.EmojiName != rerollEmoji {
+		return
+	}
+	post, appErr := p.API.GetPost(reaction.PostId)
+	if appErr != nil {
+		p.API.LogError("Failed to get the post to reroll", "post_id", reaction.PostId, "error", appErr.Error())
+		return
+	}
+	data := getRollData(post)
+	if post.UserId != p.diceBotID || data == nil || data.UserID != reaction.UserId {
+		return
+	}
+
+	newPost, appErr := p.generateRerollPost(data, false, reaction.UserId, post.ChannelId, threadRootID(post))
+	if appErr != nil {
+		p.API.LogError("Failed to reroll", "post_id", reaction.PostId, "error", appErr.Error())
+		return
+	}
+	if _, appErr = p.createDicePost(newPost); appErr != nil {
+		p.API.LogError("Failed to post the reroll", "post_id", reaction.PostId, "error", appErr.Error())
+	}
+}
+
+// generateRerollPost rolls again the roll of a dice post, as the same character and with the
+// same label. An attack is rolled again as an attack, without advantage as only the to-hit roll
+// could have it.
+func (p *Plugin) generateRerollPost(data *rollData, advantage bool, userID, channelID, rootID string) (*model.Post, *model.AppError) {
+	if data.Attack != nil {
+		return p.generateAttackPost(strings.TrimPrefix(data.rerollQuery(), attackMacroName+" "), userID, channelID, rootID)
+	}
+	var post *model.Post
+	var appErr *model.AppError
+	if advantage {
+		post, appErr = p.generateAdvantagePost(data.Character, data.rerollQuery(), userID, channelID, rootID)
+	} else {
+		post, appErr = p.generateCharacterDicePost(data.Character, data.rerollQuery(), userID, channelID, rootID)
+	}
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data.Label != "" {
+		setRollLabel(post, data.Label)
+	}
+	return post, nil
+}
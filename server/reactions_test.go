@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRerollReaction(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	dicePost, err := p.generateDicePost("3d1", "userid", "channelid", "")
+	assert.Nil(t, err)
+	dicePost.Id = "postid"
+	api.On("GetPost", "postid").Return(dicePost, nil)
+	api.On("GetPost", "otherpostid").Return(&model.Post{Id: "otherpostid", UserId: "userid"}, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	ignoredReactions := []*model.Reaction{
+		{UserId: "userid", PostId: "postid", EmojiName: "smile"},
+		{UserId: "otheruserid", PostId: "postid", EmojiName: rerollEmoji},
+		{UserId: "userid", PostId: "otherpostid", EmojiName: rerollEmoji},
+	}
+	for _, reaction := range ignoredReactions {
+		p.ReactionHasBeenAdded(&plugin.Context{}, reaction)
+	}
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+
+	p.ReactionHasBeenAdded(&plugin.Context{}, &model.Reaction{UserId: "userid", PostId: "postid", EmojiName: rerollEmoji})
+	assert.NotNil(t, post)
+	assert.Equal(t, "postid", post.RootId)
+	assert.Equal(t, "channelid", post.ChannelId)
+	assert.Equal(t, "**User** rolls `3d1` = **3**\n- `3d1`: 1 1 1", post.Message)
+}
+
+func TestRerollAttackReaction(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	// The macro must not replace the attack when it is rolled again
+	initTestMacros(api, `{"attack":"3d1"}`, "", "")
+	attackPost, err := p.generateAttackPost("+7 2d1+1 ac 16", "userid", "channelid", "")
+	assert.Nil(t, err)
+	attackPost.Id = "postid"
+	api.On("GetPost", "postid").Return(attackPost, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	seedRoller(t, 3)
+	p.ReactionHasBeenAdded(&plugin.Context{}, &model.Reaction{UserId: "userid", PostId: "postid", EmojiName: rerollEmoji})
+	assert.NotNil(t, post)
+	assert.Equal(t, "postid", post.RootId)
+	assert.Equal(t, "**Attack against AC 16**\n**User** rolls `1d20+7` = **16**\n**Hit!**\n**User** rolls `2d1+1` = **4**\n- `2d1+1`: 2 2", post.Message)
+	assert.Equal(t, "attack +7 2d1+1 ac 16", getRollData(post).Query)
+}
+
+func TestRerollCharacterReaction(t *testing.T) {
+	p, api := initTestPlugin()
+	p.diceBotID = "botid"
+	checkPost, err := p.generateCharacterDicePost("Aria", "1d1+3", "userid", "channelid", "")
+	assert.Nil(t, err)
+	setRollLabel(checkPost, "Stealth check (Dexterity +3)")
+	checkPost.Id = "postid"
+	api.On("GetPost", "postid").Return(checkPost, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	p.ReactionHasBeenAdded(&plugin.Context{}, &model.Reaction{UserId: "userid", PostId: "postid", EmojiName: rerollEmoji})
+	assert.NotNil(t, post)
+	assert.Equal(t, "**Stealth check (Dexterity +3)**\n**Aria** rolls `1d1+3` = **4**", post.Message)
+	data := getRollData(post)
+	assert.Equal(t, "Aria", data.Character)
+	assert.Equal(t, "Stealth check (Dexterity +3)", data.Label)
+
+	post, err = p.generateRerollPost(data, true, "userid", "channelid", "postid")
+	assert.Nil(t, err)
+	assert.Equal(t, "**Stealth check (Dexterity +3)**\n**Aria** rolls `1d1+3` with advantage = **4**\n- kept: **4** (`1d1+3`: 4)\n- dropped: ~~4~~ (`1d1+3`: 4)", post.Message)
+	assert.Equal(t, "Aria", getRollData(post).Character)
+}
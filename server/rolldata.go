@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+const (
+	// rollDataProp is the post prop storing the structured roll of a dice post.
+	rollDataProp string = "roll"
+	// rollDataVersion is the version of the rollData format, to increase on breaking changes.
+	rollDataVersion int = 1
+)
+
+// rollData is the structured representation of a roll, stored in the props of the dice posts
+// so that other integrations don't have to parse their Markdown text.
+type rollData struct {
+	Version int `json:"version"`
+	// Query is the query as written by the roller, and Expression the query with the macros
+	// of the roller expanded, which is rolled again by anyone
+	Query      string `json:"query"`
+	Expression string `json:"expression"`
+	UserID     string `json:"user_id"`
+	// Character is the name of the character the roll was made as, shown instead of the roller
+	Character string `json:"character,omitempty"`
+	// Label describes what the roll is for, such as 'Stealth check'
+	Label string `json:"label,omitempty"`
+	// Advantage is true when the query was rolled twice, keeping the highest total
+	Advantage bool               `json:"advantage,omitempty"`
+	Total     int                `json:"total"`
+	Requests  []dice.RequestData `json:"requests"`
+	// Dropped are the requests of the lowest roll, with advantage
+	Dropped []dice.RequestData `json:"dropped,omitempty"`
+	// Attack is the outcome of an attack roll, whose to-hit roll is the main roll
+	Attack *attackData `json:"attack,omitempty"`
+}
+
+// attackData is the outcome of an attack roll, and its damage roll if it hit.
+type attackData struct {
+	// AC is the armor class the attack was rolled against, if any
+	AC       int  `json:"ac,omitempty"`
+	Hit      bool `json:"hit"`
+	Critical bool `json:"critical,omitempty"`
+	// DamageTotal and Damage are the damage roll, with the dice doubled on a critical hit
+	DamageTotal int                `json:"damage_total,omitempty"`
+	Damage      []dice.RequestData `json:"damage,omitempty"`
+}
+
+// rerollQuery returns the query to roll again, falling back to the query as written for the
+// rolls stored before the expression.
+func (d *rollData) rerollQuery() string {
+	if d.Expression != "" {
+		return d.Expression
+	}
+	return d.Query
+}
+
+// dropRequests marks all the dice of roll requests as dropped.
+func dropRequests(requests []dice.RequestData) []dice.RequestData {
+	dropped := make([]dice.RequestData, len(requests))
+	for i, request := range requests {
+		dropped[i] = request
+		dropped[i].Dice = make([]dice.DieData, len(request.Dice))
+		for j, die := range request.Dice {
+			dropped[i].Dice[j] = die
+			dropped[i].Dice[j].Kept = false
+		}
+	}
+	return dropped
+}
+
+// setRollData stores the structured roll in the post props. It is stored as a generic map,
+// as the props are serialized between the plugin and the server.
+func setRollData(post *model.Post, data *rollData) {
+	if prop, err := toGenericMap(data); err == nil {
+		post.AddProp(rollDataProp, prop)
+	}
+}
+
+// toGenericMap converts a value to the generic map of its JSON representation.
+func toGenericMap(value any) (map[string]any, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]any
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// setRollLabel shows what a dice post was rolled for, in its message and its structured roll.
+func setRollLabel(post *model.Post, label string) {
+	post.Message = fmt.Sprintf("**%s**\n%s", label, post.Message)
+	if data := getRollData(post); data != nil {
+		data.Label = label
+		setRollData(post, data)
+	}
+}
+
+// getRollData reads the structured roll of a post, or returns nil if it has none.
+func getRollData(post *model.Post) *rollData {
+	prop := post.GetProp(rollDataProp)
+	if prop == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(prop)
+	if err != nil {
+		return nil
+	}
+	var data rollData
+	if err := json.Unmarshal(encoded, &data); err != nil || data.Version != rollDataVersion {
+		return nil
+	}
+	return &data
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+func TestRollData(t *testing.T) {
+	p, _ := initTestPlugin()
+	post, err := p.generateDicePost("2d1+3 -1 sum", "userid", "channelid", "")
+	assert.Nil(t, err)
+
+	// Props must only contain generic values to be serialized between the plugin and the server
+	assert.IsType(t, map[string]any{}, post.GetProp(rollDataProp))
+
+	assert.Equal(t, &rollData{
+		Version:    rollDataVersion,
+		Query:      "2d1+3 -1 sum",
+		Expression: "2d1+3 -1 sum",
+		UserID:     "userid",
+		Total:      7,
+		Requests: []dice.RequestData{
+			{Code: "2d1+3", Type: dice.Numeric, Sides: 1, Modifier: 3, Total: 8, Dice: []dice.DieData{
+				{Face: 1, Result: 4, Kept: true},
+				{Face: 1, Result: 4, Kept: true},
+			}},
+			{Code: "-1", Type: dice.SumModifier, Modifier: -1, Total: -1},
+		},
+	}, getRollData(post))
+}
+
+func TestRollDataAdvantage(t *testing.T) {
+	p, _ := initTestPlugin()
+	post, err := p.generateAdvantagePost("", "d1", "userid", "channelid", "")
+	assert.Nil(t, err)
+
+	data := getRollData(post)
+	assert.NotNil(t, data)
+	assert.True(t, data.Advantage)
+	assert.Equal(t, 1, data.Total)
+	assert.True(t, data.Requests[0].Dice[0].Kept)
+	assert.False(t, data.Dropped[0].Dice[0].Kept)
+}
+
+func TestRollDataJSON(t *testing.T) {
+	post := &model.Post{}
+	setRollData(post, &rollData{
+		Version:    rollDataVersion,
+		Query:      "d6",
+		Expression: "1d6",
+		UserID:     "userid",
+		Total:      4,
+		Requests:   []dice.RequestData{{Code: "d6", Type: dice.Numeric, Sides: 6, Total: 4, Dice: []dice.DieData{{Face: 4, Result: 4, Kept: true}}}},
+	})
+	encoded, err := json.Marshal(post.GetProp(rollDataProp))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"version":1,"query":"d6","expression":"1d6","user_id":"userid","total":4,"requests":[`+
+		`{"code":"d6","type":"numeric","sides":6,"total":4,"dice":[{"face":4,"result":4,"kept":true}]}]}`, string(encoded))
+}
+
+func TestRollDataMissing(t *testing.T) {
+	assert.Nil(t, getRollData(&model.Post{}))
+	post := &model.Post{}
+	post.AddProp(rollDataProp, map[string]any{"version": 42})
+	assert.Nil(t, getRollData(post))
+}
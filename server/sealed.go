@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// sealedRollsKeyPrefix prefixes the KV store keys of the sealed rolls, followed by the channel ID.
+	sealedRollsKeyPrefix = "sealed_"
+	// sealedRollsIndexKey is the KV store key of the channels with pending sealed rolls, with the
+	// time of their first sealed roll, so that the expired rolls are found without listing all the keys.
+	sealedRollsIndexKey = "sealedindex"
+	// sealedRollsCheckInterval is the delay between two checks for expired sealed rolls.
+	sealedRollsCheckInterval = time.Minute
+)
+
+// sealedRoll is a roll hidden to everyone until the sealed rolls of the channel are revealed.
+type sealedRoll struct {
+	UserID  string
+	Message string
+}
+
+// sealedRolls are the sealed rolls pending in a channel.
+type sealedRolls struct {
+	ChannelID string
+	// CreatedAt is the time of the first sealed roll, in milliseconds
+	CreatedAt int64
+	Rolls     []sealedRoll
+}
+
+// executeSealedRollCommand stores a roll hidden until the reveal, and tells the channel
+// how many sealed rolls are pending.
+func (p *Plugin) executeSealedRollCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	post, appErr := p.generateDicePost(query, args.UserId, args.ChannelId, args.RootId)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	rolls, appErr := kvUpdate(p, sealedRollsKeyPrefix+args.ChannelId, func(rolls *sealedRolls) *model.AppError {
+		if slices.ContainsFunc(rolls.Rolls, func(roll sealedRoll) bool { return roll.UserID == args.UserId }) {
+			return appError("You already made a sealed roll, wait for the reveal.", nil)
+		}
+		if len(rolls.Rolls) == 0 {
+			rolls.ChannelID = args.ChannelId
+			rolls.CreatedAt = model.GetMillis()
+		}
+		rolls.Rolls = append(rolls.Rolls, sealedRoll{UserID: args.UserId, Message: post.Message})
+		return nil
+	})
+	if appErr != nil {
+		return nil, appErr
+	}
+	if appErr := p.indexSealedRolls(args.ChannelId, rolls.CreatedAt); appErr != nil {
+		// The roll can still be revealed by a game master
+		p.API.LogError("Failed to index the sealed rolls", "channel_id", args.ChannelId, "error", appErr.Error())
+	}
+
+	displayName, appErr := p.getDisplayName(args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	pending := "1 sealed roll pending"
+	if len(rolls.Rolls) > 1 {
+		pending = fmt.Sprintf("%d sealed rolls pending", len(rolls.Rolls))
+	}
+	if _, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   fmt.Sprintf("**%s** made a sealed roll. %s.", displayName, pending),
+	}); appErr != nil {
+		return nil, appErr
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         "Your roll is sealed until a game master reveals the sealed rolls with `/roll reveal`.",
+	}, nil
+}
+
+// executeRevealCommand publishes all the sealed rolls of the channel.
+func (p *Plugin) executeRevealCommand(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	canReveal, appErr := p.canManageGame(args.ChannelId, args.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !canReveal {
+		return nil, appError("Only the game masters of this channel can reveal the sealed rolls.", nil)
+	}
+
+	revealed, appErr := p.revealSealedRolls(args.ChannelId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !revealed {
+		return nil, appError("There is no sealed roll pending in this channel.", nil)
+	}
+	return &model.CommandResponse{}, nil
+}
+
+// revealSealedRolls publishes all the sealed rolls of a channel in a single post,
+// and returns false if there were none.
+func (p *Plugin) revealSealedRolls(channelID string) (bool, *model.AppError) {
+	rolls, appErr := kvTake[sealedRolls](p, sealedRollsKeyPrefix+channelID)
+	if appErr != nil || rolls == nil {
+		return false, appErr
+	}
+	if appErr := p.unindexSealedRolls(channelID); appErr != nil {
+		p.API.LogError("Failed to remove the sealed rolls from the index", "channel_id", channelID, "error", appErr.Error())
+	}
+	if len(rolls.Rolls) == 0 {
+		return false, nil
+	}
+
+	messages := make([]string, len(rolls.Rolls))
+	for i, roll := range rolls.Rolls {
+		messages[i] = roll.Message
+	}
+	if _, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.diceBotID,
+		ChannelId: channelID,
+		Message:   fmt.Sprintf("#### Sealed rolls revealed\n%s", strings.Join(messages, "\n\n")),
+	}); appErr != nil {
+		return false, appErr
+	}
+	return true, nil
+}
+
+// watchSealedRolls regularly reveals the sealed rolls pending for too long, until stopped.
+func (p *Plugin) watchSealedRolls(stop <-chan struct{}) {
+	ticker := time.NewTicker(sealedRollsCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			p.revealExpiredSealedRolls(now)
+		}
+	}
+}
+
+// revealExpiredSealedRolls reveals the sealed rolls whose first roll is older than the configured timeout.
+func (p *Plugin) revealExpiredSealedRolls(now time.Time) {
+	timeout := time.Duration(p.getConfiguration().SealedRollsTimeout) * time.Minute
+	if timeout <= 0 {
+		return
+	}
+
+	index, appErr := kvGet[map[string]int64](p, sealedRollsIndexKey)
+	if appErr != nil {
+		p.API.LogError("Failed to read the index of the sealed rolls", "error", appErr.Error())
+		return
+	}
+	if index == nil {
+		return
+	}
+	for channelID, createdAt := range *index {
+		if now.Sub(time.UnixMilli(createdAt)) < timeout {
+			continue
+		}
+		revealed, appErr := p.revealSealedRolls(channelID)
+		if appErr != nil {
+			p.API.LogError("Failed to reveal the sealed rolls", "channel_id", channelID, "error", appErr.Error())
+			continue
+		}
+		if !revealed {
+			// The rolls were revealed by a game master in the meantime
+			if appErr := p.unindexSealedRolls(channelID); appErr != nil {
+				p.API.LogError("Failed to remove the sealed rolls from the index", "channel_id", channelID, "error", appErr.Error())
+			}
+		}
+	}
+}
+
+// indexSealedRolls records that a channel has pending sealed rolls since the given time, in milliseconds.
+func (p *Plugin) indexSealedRolls(channelID string, createdAt int64) *model.AppError {
+	_, appErr := kvUpdate(p, sealedRollsIndexKey, func(index *map[string]int64) *model.AppError {
+		if *index == nil {
+			*index = map[string]int64{}
+		}
+		(*index)[channelID] = createdAt
+		return nil
+	})
+	return appErr
+}
+
+// unindexSealedRolls records that a channel has no pending sealed rolls anymore.
+func (p *Plugin) unindexSealedRolls(channelID string) *model.AppError {
+	_, appErr := kvUpdate(p, sealedRollsIndexKey, func(index *map[string]int64) *model.AppError {
+		delete(*index, channelID)
+		return nil
+	})
+	return appErr
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSealedRolls = `{"ChannelID":"channelid","CreatedAt":1000,"Rolls":[` +
+	"{\"UserID\":\"gmid\",\"Message\":\"**GM** rolls `d20` = **3**\"}," +
+	"{\"UserID\":\"otherid\",\"Message\":\"**Other** rolls `d20` = **17**\"}]}"
+
+func TestSealedRoll(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", sealedRollsKeyPrefix+"channelid").Return([]byte(`{"ChannelID":"channelid","CreatedAt":1000,"Rolls":[{"UserID":"gmid","Message":"hidden"}]}`), nil)
+	var saved []byte
+	api.On("KVCompareAndSet", sealedRollsKeyPrefix+"channelid", mock.Anything, mock.Anything).Return(true, nil).Run(func(args mock.Arguments) {
+		saved = args.Get(2).([]byte)
+	})
+	api.On("KVGet", sealedRollsIndexKey).Return([]byte(`{"otherid":500}`), nil)
+	api.On("KVCompareAndSet", sealedRollsIndexKey, []byte(`{"otherid":500}`), []byte(`{"channelid":1000,"otherid":500}`)).Return(true, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
+		post = args.Get(0).(*model.Post)
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll sealed 2d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+	assert.Equal(t, "**User** made a sealed roll. 2 sealed rolls pending.", post.Message)
+	assert.Equal(t, `{"ChannelID":"channelid","CreatedAt":1000,"Rolls":[{"UserID":"gmid","Message":"hidden"},`+
+		"{\"UserID\":\"userid\",\"Message\":\"**User** rolls `2d1` = **2**\\n- `2d1`: 1 1\"}]}", string(saved))
+
+	// A second sealed roll by the same user is refused
+	api.ExpectedCalls = nil
+	api.On("GetUser", mock.Anything).Return(&model.User{Id: "userid", Nickname: "User"}, nil)
+	api.On("KVGet", sealedRollsKeyPrefix+"channelid").Return(saved, nil)
+	response, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll sealed 2d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+}
+
+func TestRevealSealedRolls(t *testing.T) {
+	p, api := initTestPlugin()
+	initTestGM(api)
+	api.On("KVGet", sealedRollsKeyPrefix+"channelid").Return([]byte(testSealedRolls), nil)
+	api.On("KVCompareAndDelete", sealedRollsKeyPrefix+"channelid", []byte(testSealedRolls)).Return(true, nil)
+	api.On("KVGet", sealedRollsIndexKey).Return([]byte(`{"channelid":1000}`), nil)
+	api.On("KVCompareAndSet", sealedRollsIndexKey, []byte(`{"channelid":1000}`), []byte(`{}`)).Return(true, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
+		post = args.Get(0).(*model.Post)
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll reveal",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+	assert.Nil(t, post)
+
+	response, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll reveal",
+		UserId:    "gmid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, "#### Sealed rolls revealed\n**GM** rolls `d20` = **3**\n\n**Other** rolls `d20` = **17**", post.Message)
+}
+
+func TestRevealExpiredSealedRolls(t *testing.T) {
+	p, api := initTestPlugin()
+	p.setConfiguration(&configuration{SealedRollsTimeout: 1})
+	api.On("KVGet", sealedRollsIndexKey).Return([]byte(`{"channelid":1000,"revealedid":1000}`), nil)
+	api.On("KVCompareAndSet", sealedRollsIndexKey, []byte(`{"channelid":1000,"revealedid":1000}`), mock.Anything).Return(true, nil)
+	api.On("KVGet", sealedRollsKeyPrefix+"channelid").Return([]byte(testSealedRolls), nil)
+	api.On("KVCompareAndDelete", sealedRollsKeyPrefix+"channelid", []byte(testSealedRolls)).Return(true, nil)
+	// The sealed rolls of this channel were already revealed by a game master
+	api.On("KVGet", sealedRollsKeyPrefix+"revealedid").Return(nil, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+
+	p.revealExpiredSealedRolls(time.UnixMilli(1000).Add(30 * time.Second))
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+
+	p.revealExpiredSealedRolls(time.UnixMilli(1000).Add(time.Minute))
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+	api.AssertNumberOfCalls(t, "KVCompareAndSet", 2)
+	api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// maxSheetSize limits the size of the imported character sheets, in bytes.
+	maxSheetSize int = 64 * 1024
+	// sheetFilesPerPage is the number of recent files of the user searched for a sheet to import.
+	sheetFilesPerPage int = 20
+	// maxAbilityScore and maxProficiency bound the values of the imported character sheets.
+	maxAbilityScore int = 30
+	maxProficiency  int = 10
+)
+
+// ability is one of the six abilities of a 5e character.
+type ability struct {
+	code string
+	name string
+}
+
+var abilities = []ability{
+	{code: "str", name: "Strength"},
+	{code: "dex", name: "Dexterity"},
+	{code: "con", name: "Constitution"},
+	{code: "int", name: "Intelligence"},
+	{code: "wis", name: "Wisdom"},
+	{code: "cha", name: "Charisma"},
+}
+
+// skillAbilities are the abilities used by the 5e skills.
+var skillAbilities = map[string]string{
+	"acrobatics":      "dex",
+	"animal_handling": "wis",
+	"arcana":          "int",
+	"athletics":       "str",
+	"deception":       "cha",
+	"history":         "int",
+	"insight":         "wis",
+	"intimidation":    "cha",
+	"investigation":   "int",
+	"medicine":        "wis",
+	"nature":          "int",
+	"perception":      "wis",
+	"performance":     "cha",
+	"persuasion":      "cha",
+	"religion":        "int",
+	"sleight_of_hand": "dex",
+	"stealth":         "dex",
+	"survival":        "wis",
+}
+
+// characterSheet holds what is needed to roll the checks and saving throws of a 5e character.
+type characterSheet struct {
+	Name string `json:"name"`
+	// Abilities are the ability scores, by ability code such as 'dex'
+	Abilities   map[string]int `json:"abilities"`
+	Proficiency int            `json:"proficiency"`
+	// Skills are the proficient skills, and Expertise those whose proficiency bonus is doubled
+	Skills    []string `json:"skills,omitempty"`
+	Expertise []string `json:"expertise,omitempty"`
+	// Saves are the abilities of the proficient saving throws
+	Saves []string `json:"saves,omitempty"`
+}
+
+// findAbility returns the ability matching a code or a name, such as 'dex' or 'Dexterity'.
+func findAbility(codeOrName string) *ability {
+	for i, ability := range abilities {
+		if strings.EqualFold(codeOrName, ability.code) || strings.EqualFold(codeOrName, ability.name) {
+			return &abilities[i]
+		}
+	}
+	return nil
+}
+
+// normalizeSkill turns a skill name such as 'Sleight of Hand' into its key 'sleight_of_hand'.
+func normalizeSkill(skill string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(skill)))
+}
+
+// formatSkill turns a skill key such as 'sleight_of_hand' into a name such as 'Sleight of hand'.
+func formatSkill(skill string) string {
+	name := strings.ReplaceAll(skill, "_", " ")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func abilityModifier(score int) int {
+	return score/2 - 5
+}
+
+// parseCharacterSheet reads a JSON character sheet, accepting ability names and skill names
+// as written on the sheet.
+func parseCharacterSheet(data []byte) (*characterSheet, *model.AppError) {
+	var raw characterSheet
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, appError("The character sheet is not valid JSON.", err)
+	}
+	sheet := &characterSheet{Name: strings.TrimSpace(raw.Name), Abilities: map[string]int{}, Proficiency: raw.Proficiency}
+	if sheet.Name == "" {
+		return nil, appError("The character sheet must have a `name`.", nil)
+	}
+	if !plainTextRegexp.MatchString(sheet.Name) {
+		return nil, appError("Character names may only contain "+plainTextRule+".", nil)
+	}
+	if sheet.Proficiency < 0 || sheet.Proficiency > maxProficiency {
+		return nil, appError(fmt.Sprintf("The proficiency bonus must be between 0 and %d.", maxProficiency), nil)
+	}
+	for key, score := range raw.Abilities {
+		ability := findAbility(key)
+		if ability == nil {
+			return nil, appError(fmt.Sprintf("Unknown ability `%s` in the character sheet.", key), nil)
+		}
+		if score < 1 || score > maxAbilityScore {
+			return nil, appError(fmt.Sprintf("The %s score must be between 1 and %d.", ability.name, maxAbilityScore), nil)
+		}
+		sheet.Abilities[ability.code] = score
+	}
+	for _, list := range []struct{ raw, normalized *[]string }{
+		{raw: &raw.Skills, normalized: &sheet.Skills},
+		{raw: &raw.Expertise, normalized: &sheet.Expertise},
+	} {
+		for _, skill := range *list.raw {
+			key := normalizeSkill(skill)
+			if _, ok := skillAbilities[key]; !ok {
+				return nil, appError(fmt.Sprintf("Unknown skill `%s` in the character sheet.", skill), nil)
+			}
+			*list.normalized = append(*list.normalized, key)
+		}
+	}
+	for _, save := range raw.Saves {
+		ability := findAbility(save)
+		if ability == nil {
+			return nil, appError(fmt.Sprintf("Unknown saving throw `%s` in the character sheet.", save), nil)
+		}
+		sheet.Saves = append(sheet.Saves, ability.code)
+	}
+	return sheet, nil
+}
+
+// summary describes the character, such as 'Aria: STR 8 (-1) · DEX 16 (+3) · proficiency +2'.
+func (s *characterSheet) summary() string {
+	scores := []string{}
+	for _, ability := range abilities {
+		if score, ok := s.Abilities[ability.code]; ok {
+			scores = append(scores, fmt.Sprintf("%s %d (%+d)", strings.ToUpper(ability.code), score, abilityModifier(score)))
+		}
+	}
+	scores = append(scores, fmt.Sprintf("proficiency %+d", s.Proficiency))
+	return fmt.Sprintf("**%s**: %s", s.Name, strings.Join(scores, " · "))
+}
+
+// rollTerm is a modifier of a check, such as 'Dexterity +3'.
+type rollTerm struct {
+	name  string
+	value int
+}
+
+// checkTerms returns the label and the modifiers of an ability check or a skill check.
+func (s *characterSheet) checkTerms(skillOrAbility string) (string, []rollTerm, *model.AppError) {
+	if ability := findAbility(skillOrAbility); ability != nil {
+		term, appErr := s.abilityTerm(ability)
+		if appErr != nil {
+			return "", nil, appErr
+		}
+		return ability.name + " check", []rollTerm{*term}, nil
+	}
+
+	skill := normalizeSkill(skillOrAbility)
+	abilityCode, ok := skillAbilities[skill]
+	if !ok {
+		return "", nil, appError(fmt.Sprintf("Unknown skill or ability `%s`.", skillOrAbility), nil)
+	}
+	term, appErr := s.abilityTerm(findAbility(abilityCode))
+	if appErr != nil {
+		return "", nil, appErr
+	}
+	terms := []rollTerm{*term}
+	switch {
+	case slices.Contains(s.Expertise, skill):
+		terms = append(terms, rollTerm{name: "expertise", value: 2 * s.Proficiency})
+	case slices.Contains(s.Skills, skill):
+		terms = append(terms, rollTerm{name: "proficiency", value: s.Proficiency})
+	}
+	return formatSkill(skill) + " check", terms, nil
+}
+
+// saveTerms returns the label and the modifiers of a saving throw.
+func (s *characterSheet) saveTerms(abilityName string) (string, []rollTerm, *model.AppError) {
+	ability := findAbility(abilityName)
+	if ability == nil {
+		return "", nil, appError(fmt.Sprintf("Unknown ability `%s`.", abilityName), nil)
+	}
+	term, appErr := s.abilityTerm(ability)
+	if appErr != nil {
+		return "", nil, appErr
+	}
+	terms := []rollTerm{*term}
+	if slices.Contains(s.Saves, ability.code) {
+		terms = append(terms, rollTerm{name: "proficiency", value: s.Proficiency})
+	}
+	return ability.name + " saving throw", terms, nil
+}
+
+func (s *characterSheet) abilityTerm(ability *ability) (*rollTerm, *model.AppError) {
+	score, ok := s.Abilities[ability.code]
+	if !ok {
+		return nil, appError(fmt.Sprintf("The character sheet of %s has no %s score.", s.Name, ability.name), nil)
+	}
+	return &rollTerm{name: ability.name, value: abilityModifier(score)}, nil
+}
+
+// executeSheetCommand handles '/roll sheet import [JSON]'.
+func (p *Plugin) executeSheetCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	action, data := splitSubcommand(query)
+	if action != "import" {
+		return nil, appError("Use `/roll sheet import` after posting your character sheet as a JSON file in this channel, or `/roll sheet import {...}` with the JSON sheet.", nil)
+	}
+	if data == "" {
+		var appErr *model.AppError
+		if data, appErr = p.readLatestSheetFile(args.UserId, args.ChannelId); appErr != nil {
+			return nil, appErr
+		}
+	}
+	if len(data) > maxSheetSize {
+		return nil, appError(fmt.Sprintf("The character sheet is too large; maximum is %d KB.", maxSheetSize/1024), nil)
+	}
+	sheet, appErr := parseCharacterSheet([]byte(data))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if appErr := p.saveCharacter(args.UserId, args.ChannelId, sheet); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         fmt.Sprintf("Character sheet imported. %s\n%s is now your active character in this channel.", sheet.summary(), sheet.Name),
+	}, nil
+}
+
+// readLatestSheetFile returns the content of the latest JSON file posted by the user in the channel.
+func (p *Plugin) readLatestSheetFile(userID, channelID string) (string, *model.AppError) {
+	fileInfos, appErr := p.API.GetFileInfos(0, sheetFilesPerPage, &model.GetFileInfosOptions{
+		UserIds:        []string{userID},
+		ChannelIds:     []string{channelID},
+		SortBy:         model.FileinfoSortByCreated,
+		SortDescending: true,
+	})
+	if appErr != nil {
+		return "", appErr
+	}
+	for _, fileInfo := range fileInfos {
+		if !strings.EqualFold(fileInfo.Extension, "json") {
+			continue
+		}
+		if fileInfo.Size > int64(maxSheetSize) {
+			return "", appError(fmt.Sprintf("The character sheet is too large; maximum is %d KB.", maxSheetSize/1024), nil)
+		}
+		data, appErr := p.API.GetFile(fileInfo.Id)
+		if appErr != nil {
+			return "", appErr
+		}
+		return string(data), nil
+	}
+	return "", appError("No JSON file found. Post your character sheet as a JSON file in this channel, then use `/roll sheet import`.", nil)
+}
+
+// executeCheckCommand handles '/roll check <skill or ability>' for the active character of the user.
+func (p *Plugin) executeCheckCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	sheet, appErr := p.getActiveCharacter(args.UserId, args.ChannelId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return p.executeSheetCheck(args, sheet, query)
+}
+
+func (p *Plugin) executeSheetCheck(args *model.CommandArgs, sheet *characterSheet, query string) (*model.CommandResponse, *model.AppError) {
+	if query == "" {
+		return nil, appError("Use `/roll check <skill or ability>`, for example `/roll check stealth`.", nil)
+	}
+	label, terms, appErr := sheet.checkTerms(query)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return p.executeSheetRoll(args, sheet, label, terms)
+}
+
+// executeSaveCommand handles '/roll save <ability>' for the active character of the user.
+func (p *Plugin) executeSaveCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	sheet, appErr := p.getActiveCharacter(args.UserId, args.ChannelId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return p.executeSheetSave(args, sheet, query)
+}
+
+func (p *Plugin) executeSheetSave(args *model.CommandArgs, sheet *characterSheet, query string) (*model.CommandResponse, *model.AppError) {
+	if query == "" {
+		return nil, appError("Use `/roll save <ability>`, for example `/roll save wis`.", nil)
+	}
+	label, terms, appErr := sheet.saveTerms(query)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return p.executeSheetRoll(args, sheet, label, terms)
+}
+
+// executeSheetRoll rolls a d20 as a character with the modifiers computed from its sheet,
+// labelled with the origin of each modifier.
+func (p *Plugin) executeSheetRoll(args *model.CommandArgs, sheet *characterSheet, label string, terms []rollTerm) (*model.CommandResponse, *model.AppError) {
+	query := "1d20"
+	details := make([]string, len(terms))
+	for i, term := range terms {
+		query += fmt.Sprintf("%+d", term.value)
+		details[i] = fmt.Sprintf("%s %+d", term.name, term.value)
+	}
+
+	post, appErr := p.generateCharacterDicePost(sheet.Name, query, args.UserId, args.ChannelId, args.RootId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	setRollLabel(post, fmt.Sprintf("%s (%s)", label, strings.Join(details, ", ")))
+	if _, appErr := p.createDicePost(post); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{}, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSheet = `{
+	"name": "Aria",
+	"abilities": {"Strength": 8, "dex": 16, "CON": 12, "int": 10, "wis": 13, "cha": 15},
+	"proficiency": 2,
+	"skills": ["Perception", "Sleight of Hand"],
+	"expertise": ["stealth"],
+	"saves": ["Dexterity", "int"]
+}`
+
+const testStoredSheet = `{"name":"Aria","abilities":{"cha":15,"con":12,"dex":16,"int":10,"str":8,"wis":13},"proficiency":2,"skills":["perception","sleight_of_hand"],"expertise":["stealth"],"saves":["dex","int"]}`
+
+func TestParseCharacterSheet(t *testing.T) {
+	sheet, err := parseCharacterSheet([]byte(testSheet))
+	assert.Nil(t, err)
+	assert.Equal(t, "Aria", sheet.Name)
+	assert.Equal(t, map[string]int{"str": 8, "dex": 16, "con": 12, "int": 10, "wis": 13, "cha": 15}, sheet.Abilities)
+	assert.Equal(t, []string{"perception", "sleight_of_hand"}, sheet.Skills)
+	assert.Equal(t, []string{"dex", "int"}, sheet.Saves)
+	assert.Equal(t, "**Aria**: STR 8 (-1) · DEX 16 (+3) · CON 12 (+1) · INT 10 (+0) · WIS 13 (+1) · CHA 15 (+2) · proficiency +2", sheet.summary())
+
+	for _, bad := range []string{
+		`not json`,
+		`{"abilities": {"dex": 12}}`,
+		`{"name": "Aria", "abilities": {"luck": 12}}`,
+		`{"name": "Aria", "abilities": {"dex": 31}}`,
+		`{"name": "Aria", "proficiency": -1}`,
+		`{"name": "Aria", "skills": ["flying"]}`,
+		`{"name": "Aria", "saves": ["luck"]}`,
+	} {
+		_, err := parseCharacterSheet([]byte(bad))
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestCharacterSheetTerms(t *testing.T) {
+	sheet, _ := parseCharacterSheet([]byte(testSheet))
+	for _, testCase := range []struct {
+		save  bool
+		name  string
+		label string
+		terms []rollTerm
+	}{
+		{name: "stealth", label: "Stealth check", terms: []rollTerm{{"Dexterity", 3}, {"expertise", 4}}},
+		{name: "sleight-of-hand", label: "Sleight of hand check", terms: []rollTerm{{"Dexterity", 3}, {"proficiency", 2}}},
+		{name: "Athletics", label: "Athletics check", terms: []rollTerm{{"Strength", -1}}},
+		{name: "cha", label: "Charisma check", terms: []rollTerm{{"Charisma", 2}}},
+		{save: true, name: "wis", label: "Wisdom saving throw", terms: []rollTerm{{"Wisdom", 1}}},
+		{save: true, name: "Intelligence", label: "Intelligence saving throw", terms: []rollTerm{{"Intelligence", 0}, {"proficiency", 2}}},
+	} {
+		terms := sheet.checkTerms
+		if testCase.save {
+			terms = sheet.saveTerms
+		}
+		label, rollTerms, err := terms(testCase.name)
+		assert.Nil(t, err, testCase.name)
+		assert.Equal(t, testCase.label, label, testCase.name)
+		assert.Equal(t, testCase.terms, rollTerms, testCase.name)
+	}
+
+	_, _, err := sheet.checkTerms("flying")
+	assert.NotNil(t, err)
+	_, _, err = sheet.saveTerms("stealth")
+	assert.NotNil(t, err)
+}
+
+func TestImportSheet(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", charactersKeyPrefix+"userid").Return(nil, nil)
+	api.On("KVCompareAndSet", charactersKeyPrefix+"userid", []byte(nil), []byte(`{"aria":`+testStoredSheet+`}`)).Return(true, nil)
+	api.On("KVSet", activeCharacterKeyPrefix+"channelid_userid", []byte(`"aria"`)).Return(nil)
+
+	args := &model.CommandArgs{Command: "/roll sheet import " + testSheet, UserId: "userid", ChannelId: "channelid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "Character sheet imported. **Aria**: STR 8 (-1)")
+	assert.Contains(t, response.Text, "\nAria is now your active character in this channel.")
+
+	api.On("GetFileInfos", 0, sheetFilesPerPage, mock.AnythingOfType("*model.GetFileInfosOptions")).Return([]*model.FileInfo{
+		{Id: "imageid", Extension: "png"},
+		{Id: "sheetid", Extension: "JSON", Size: int64(len(testSheet))},
+	}, nil)
+	api.On("GetFile", "sheetid").Return([]byte(testSheet), nil)
+	args.Command = "/roll sheet import"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "Character sheet imported.")
+
+	args.Command = "/roll sheet"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+}
+
+func TestImportSheetWithoutFile(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("GetFileInfos", 0, sheetFilesPerPage, mock.AnythingOfType("*model.GetFileInfosOptions")).Return([]*model.FileInfo{}, nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll sheet import", UserId: "userid", ChannelId: "channelid"})
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+	assert.Contains(t, err.Message, "No JSON file found.")
+}
+
+func TestRollCheckAndSave(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	api.On("KVGet", charactersKeyPrefix+"userid").Return([]byte(`{"aria":`+testStoredSheet+`}`), nil)
+	api.On("KVGet", activeCharacterKeyPrefix+"channelid_userid").Return(nil, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	args := &model.CommandArgs{Command: "/roll check stealth", UserId: "userid", ChannelId: "channelid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Regexp(t, `^\*\*Stealth check \(Dexterity \+3, expertise \+4\)\*\*\n\*\*Aria\*\* rolls `+"`1d20\\+3\\+4`"+` = \*\*\d+\*\*$`, post.Message)
+	assert.Equal(t, "Stealth check (Dexterity +3, expertise +4)", getRollData(post).Label)
+
+	args.Command = "/roll save wis"
+	_, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Regexp(t, `^\*\*Wisdom saving throw \(Wisdom \+1\)\*\*\n`, post.Message)
+
+	for _, command := range []string{"/roll check", "/roll check flying", "/roll save", "/roll save stealth"} {
+		args.Command = command
+		response, err = p.ExecuteCommand(&plugin.Context{}, args)
+		assert.NotNil(t, err, command)
+		assert.Nil(t, response, command)
+	}
+}
+
+func TestRollCheckWithoutSheet(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", charactersKeyPrefix+"userid").Return(nil, nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll check stealth", UserId: "userid", ChannelId: "channelid"})
+	assert.Nil(t, response)
+	assert.Equal(t, "You have no character sheet. Import one with `/roll sheet import`.", err.Message)
+}
+
+func TestCharacterSheetName(t *testing.T) {
+	for _, name := range []string{"Aria", "Goblin Boss", "Zoë d'Arc", "Sir Bors (the 2nd)"} {
+		_, err := parseCharacterSheet([]byte(`{"name": "` + name + `"}`))
+		assert.Nil(t, err, name)
+	}
+	for _, name := range []string{"@channel", "Aria @all", "[Aria](https://example.com)", "**Aria**", "Aria_", "`Aria`", "-Aria", "Aria\\nhere"} {
+		_, err := parseCharacterSheet([]byte(`{"name": "` + name + `"}`))
+		assert.NotNil(t, err, name)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/moussetc/mattermost-plugin-dice-roller/dice"
+)
+
+const (
+	// userVariablesKeyPrefix prefixes the KV store keys of the variables of a user, followed by
+	// the user ID. channelVariablesKeyPrefix prefixes the keys of the variables of a user in a
+	// channel, followed by the channel ID and the user ID.
+	userVariablesKeyPrefix    string = "vars_"
+	channelVariablesKeyPrefix string = "channelvars_"
+	maxVariableNameLength     int    = 32
+	// channelVariableFlag sets or unsets a variable in the current channel only.
+	channelVariableFlag string = "--channel"
+)
+
+var variableNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// reservedVariableNames are the special mentions of Mattermost, which cannot be variable names.
+var reservedVariableNames = []string{"all", "channel", "here"}
+
+func userVariablesKey(userID string) string {
+	return userVariablesKeyPrefix + userID
+}
+
+func channelVariablesKey(userID, channelID string) string {
+	return channelVariablesKeyPrefix + channelID + "_" + userID
+}
+
+func (p *Plugin) getStoredVariables(key string) (map[string]int, *model.AppError) {
+	variables, appErr := kvGet[map[string]int](p, key)
+	if appErr != nil || variables == nil {
+		return map[string]int{}, appErr
+	}
+	return *variables, nil
+}
+
+// getVariableResolver resolves the variables of a user in a query: the variables set in the
+// channel override the variables of the user. The KV store is only read when the query uses variables.
+func (p *Plugin) getVariableResolver(userID, channelID, query string) (dice.VariableResolver, *model.AppError) {
+	if !strings.Contains(query, "@") {
+		return nil, nil
+	}
+	variables, appErr := p.getStoredVariables(userVariablesKey(userID))
+	if appErr != nil {
+		return nil, appErr
+	}
+	channelVariables, appErr := p.getStoredVariables(channelVariablesKey(userID, channelID))
+	if appErr != nil {
+		return nil, appErr
+	}
+	maps.Copy(variables, channelVariables)
+	return func(name string) (int, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}, nil
+}
+
+// readVariableScope reads the optional flag setting a variable in the channel only,
+// and returns the KV store key of the variables along with the remaining fields.
+func readVariableScope(args *model.CommandArgs, fields []string) (string, []string) {
+	if len(fields) > 0 && fields[0] == channelVariableFlag {
+		return channelVariablesKey(args.UserId, args.ChannelId), fields[1:]
+	}
+	return userVariablesKey(args.UserId), fields
+}
+
+// executeSetVariableCommand handles '/roll set [--channel] <name> <value>'.
+func (p *Plugin) executeSetVariableCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	key, fields := readVariableScope(args, strings.Fields(query))
+	if len(fields) != 2 {
+		return nil, appError("Use `/roll set [--channel] <name> <value>`, for example `/roll set dex 3`.", nil)
+	}
+	name := strings.ToLower(strings.TrimPrefix(fields[0], "@"))
+	if len(name) > maxVariableNameLength || !variableNameRegexp.MatchString(name) {
+		return nil, appError(fmt.Sprintf("Variable names must only contain lowercase letters, digits and underscores, and not start with a digit (up to %d characters).", maxVariableNameLength), nil)
+	}
+	if slices.Contains(reservedVariableNames, name) {
+		return nil, appError(fmt.Sprintf("`@%s` is a special mention of Mattermost and cannot be a variable name.", name), nil)
+	}
+	value, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, appError(fmt.Sprintf("'%s' is not a valid value, use a whole number such as `3` or `-1`.", fields[1]), err)
+	}
+
+	if _, appErr := kvUpdate(p, key, func(variables *map[string]int) *model.AppError {
+		if *variables == nil {
+			*variables = map[string]int{}
+		}
+		(*variables)[name] = value
+		return nil
+	}); appErr != nil {
+		return nil, appErr
+	}
+
+	text := fmt.Sprintf("`@%s` is now %d. Use it as `/roll 1d20+@%s`.", name, value, name)
+	if key != userVariablesKey(args.UserId) {
+		text = fmt.Sprintf("`@%s` is now %d in this channel. Use it as `/roll 1d20+@%s`.", name, value, name)
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
+
+// executeUnsetVariableCommand handles '/roll unset [--channel] <name>'.
+func (p *Plugin) executeUnsetVariableCommand(args *model.CommandArgs, query string) (*model.CommandResponse, *model.AppError) {
+	key, fields := readVariableScope(args, strings.Fields(query))
+	if len(fields) != 1 {
+		return nil, appError("Use `/roll unset [--channel] <name>`, for example `/roll unset dex`.", nil)
+	}
+	name := strings.ToLower(strings.TrimPrefix(fields[0], "@"))
+
+	if _, appErr := kvUpdate(p, key, func(variables *map[string]int) *model.AppError {
+		if _, ok := (*variables)[name]; !ok {
+			return appError(fmt.Sprintf("The variable `@%s` is not set.", name), nil)
+		}
+		delete(*variables, name)
+		return nil
+	}); appErr != nil {
+		return nil, appErr
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         fmt.Sprintf("`@%s` is no longer set.", name),
+	}, nil
+}
+
+// executeListVariablesCommand lists the variables of the user, and those set in the channel.
+func (p *Plugin) executeListVariablesCommand(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	sections := []string{}
+	for _, section := range []struct{ title, key string }{
+		{title: "Your variables", key: userVariablesKey(args.UserId)},
+		{title: "Your variables in this channel", key: channelVariablesKey(args.UserId, args.ChannelId)},
+	} {
+		variables, appErr := p.getStoredVariables(section.key)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if len(variables) == 0 {
+			continue
+		}
+		text := section.title + ":"
+		for _, name := range slices.Sorted(maps.Keys(variables)) {
+			text += fmt.Sprintf("\n- `@%s`: %d", name, variables[name])
+		}
+		sections = append(sections, text)
+	}
+	text := "You have no variables. Set one with `/roll set dex 3`."
+	if len(sections) > 0 {
+		text = strings.Join(sections, "\n\n")
+	}
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
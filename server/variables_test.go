@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollVariables(t *testing.T) {
+	p, api := initTestPlugin()
+	assert.Nil(t, p.OnActivate())
+	api.On("KVGet", userVariablesKeyPrefix+"userid").Return([]byte(`{"dex":3,"prof":2}`), nil)
+	api.On("KVGet", channelVariablesKeyPrefix+"channelid_userid").Return([]byte(`{"dex":4}`), nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll 2d1+@dex+@prof @prof",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, "**User** rolls `2d1+@dex+@prof @prof` = **16**\n- `2d1+@dex+@prof`: 7 7\n- +2", post.Message)
+
+	response, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll 1d20+@wis",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "The variable '@wis' is not defined.\n```\n1d20+@wis\n     ^^^^\n```\nSet it with `/roll set wis <value>`.", response.Text)
+}
+
+func TestSetVariable(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", userVariablesKeyPrefix+"userid").Return(nil, nil)
+	api.On("KVCompareAndSet", userVariablesKeyPrefix+"userid", []byte(nil), []byte(`{"dex":-1}`)).Return(true, nil)
+	api.On("KVGet", channelVariablesKeyPrefix+"channelid_userid").Return([]byte(`{"dex":4}`), nil)
+	api.On("KVCompareAndSet", channelVariablesKeyPrefix+"channelid_userid", []byte(`{"dex":4}`), []byte(`{}`)).Return(true, nil)
+
+	args := &model.CommandArgs{Command: "/roll set @Dex -1", UserId: "userid", ChannelId: "channelid"}
+	response, err := p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "`@dex` is now -1. Use it as `/roll 1d20+@dex`.", response.Text)
+
+	args.Command = "/roll unset --channel dex"
+	response, err = p.ExecuteCommand(&plugin.Context{}, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "`@dex` is no longer set.", response.Text)
+
+	for _, command := range []string{"/roll set dex", "/roll set dex three", "/roll set 2dex 3", "/roll set all 1", "/roll set @here 1", "/roll set channel 1", "/roll unset wis", "/roll unset"} {
+		args.Command = command
+		response, err = p.ExecuteCommand(&plugin.Context{}, args)
+		assert.NotNil(t, err, command)
+		assert.Nil(t, response, command)
+	}
+}
+
+func TestListVariables(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("KVGet", userVariablesKeyPrefix+"userid").Return([]byte(`{"prof":2,"dex":3}`), nil)
+	api.On("KVGet", channelVariablesKeyPrefix+"channelid_userid").Return([]byte(`{"dex":4}`), nil)
+	api.On("KVGet", userVariablesKeyPrefix+"otherid").Return(nil, nil)
+	api.On("KVGet", channelVariablesKeyPrefix+"channelid_otherid").Return(nil, nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll vars", UserId: "userid", ChannelId: "channelid"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Your variables:\n- `@dex`: 3\n- `@prof`: 2\n\nYour variables in this channel:\n- `@dex`: 4", response.Text)
+
+	response, err = p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/roll vars", UserId: "otherid", ChannelId: "channelid"})
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "You have no variables.")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// headerWebhookSignature carries the HMAC-SHA256 of the webhook payloads.
+	headerWebhookSignature = "X-Dice-Roller-Signature"
+	webhookMaxAttempts     = 3
+	webhookTimeout         = 10 * time.Second
+	// webhookShutdownTimeout bounds the wait for the pending webhook requests on deactivation.
+	webhookShutdownTimeout = 5 * time.Second
+)
+
+// webhookRetryDelay is the delay before the first retry of a failed webhook request,
+// doubled after each attempt.
+var webhookRetryDelay = 2 * time.Second
+
+// webhookPayload is the body of the requests sent to the webhooks, and the payload of the
+// roll_result WebSocket events.
+type webhookPayload struct {
+	PostID    string    `json:"post_id"`
+	ChannelID string    `json:"channel_id"`
+	Roll      *rollData `json:"roll"`
+}
+
+// sendRollWebhooks asynchronously sends the structured roll of a dice post to the
+// configured webhooks, unless the post is in a direct or group message.
+func (p *Plugin) sendRollWebhooks(post *model.Post) {
+	config := p.getConfiguration()
+	urls := config.webhookURLs()
+	if len(urls) == 0 {
+		return
+	}
+	if channelIDs := config.webhookChannelIDs(); len(channelIDs) > 0 && !slices.Contains(channelIDs, post.ChannelId) {
+		return
+	}
+	data := getRollData(post)
+	if data == nil {
+		return
+	}
+	// The rolls of the direct and group messages, such as the whispered rolls, are not public
+	channel, appErr := p.API.GetChannel(post.ChannelId)
+	if appErr != nil {
+		p.API.LogError("Failed to get the channel of the roll to send to the webhooks", "channel_id", post.ChannelId, "error", appErr.Error())
+		return
+	}
+	if channel.IsGroupOrDirect() {
+		return
+	}
+	body, err := json.Marshal(&webhookPayload{PostID: post.Id, ChannelID: post.ChannelId, Roll: data})
+	if err != nil {
+		p.API.LogError("Failed to encode the webhook payload", "error", err.Error())
+		return
+	}
+	signature := ""
+	if config.WebhookSecret != "" {
+		signature = signWebhookPayload(config.WebhookSecret, body)
+	}
+
+	for _, url := range urls {
+		p.webhooks.Add(1)
+		go func() {
+			defer p.webhooks.Done()
+			if err := sendWebhook(url, body, signature, p.stopWebhooks); err != nil {
+				p.API.LogWarn("Failed to send the roll to a webhook", "url", url, "error", err.Error())
+			}
+		}()
+	}
+}
+
+// signWebhookPayload returns the signature of a webhook payload, as 'sha256=<hexadecimal HMAC>'.
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendWebhook posts the payload to a webhook, retrying on network and server errors
+// until the stop channel is closed.
+func sendWebhook(url string, body []byte, signature string, stop <-chan struct{}) error {
+	client := &http.Client{Timeout: webhookTimeout}
+	delay := webhookRetryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = postWebhook(client, url, body, signature)
+		if err == nil || attempt == webhookMaxAttempts {
+			return err
+		}
+		if errors.As(err, new(permanentWebhookError)) {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			return fmt.Errorf("the plugin was deactivated before retrying: %w", err)
+		}
+		delay *= 2
+	}
+}
+
+// permanentWebhookError is an error that retrying would not fix, such as a 4xx response.
+type permanentWebhookError struct {
+	error
+}
+
+func postWebhook(client *http.Client, url string, body []byte, signature string) error {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return permanentWebhookError{err}
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if signature != "" {
+		request.Header.Set(headerWebhookSignature, signature)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	switch {
+	case response.StatusCode >= 500:
+		return fmt.Errorf("the webhook answered with status %d", response.StatusCode)
+	case response.StatusCode >= 400:
+		return permanentWebhookError{fmt.Errorf("the webhook answered with status %d", response.StatusCode)}
+	}
+	return nil
+}
+
+// waitForWebhooks waits for the pending webhook requests, for a limited time.
+func (p *Plugin) waitForWebhooks(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		p.webhooks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		p.API.LogWarn("Some webhook requests were still pending on deactivation")
+	}
+}
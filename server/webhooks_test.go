@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookRecorder is a webhook server failing with the given status codes before succeeding.
+type webhookRecorder struct {
+	lock        sync.Mutex
+	failures    []int
+	attempts    int
+	bodies      [][]byte
+	signatures  []string
+	contentType string
+}
+
+func (rec *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	rec.bodies = append(rec.bodies, body)
+	rec.signatures = append(rec.signatures, r.Header.Get(headerWebhookSignature))
+	rec.contentType = r.Header.Get("Content-Type")
+	if rec.attempts < len(rec.failures) {
+		w.WriteHeader(rec.failures[rec.attempts])
+	}
+	rec.attempts++
+}
+
+func initTestWebhook(t *testing.T, config *configuration, failures ...int) (*Plugin, *webhookRecorder, *model.Post) {
+	previousDelay := webhookRetryDelay
+	webhookRetryDelay = time.Millisecond
+	t.Cleanup(func() { webhookRetryDelay = previousDelay })
+	recorder := &webhookRecorder{failures: failures}
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+
+	p, api := initTestPlugin()
+	api.On("LogWarn", "Failed to send the roll to a webhook", "url", server.URL, "error", "the webhook answered with status 400").Return()
+	api.On("GetChannel", "channelid").Return(&model.Channel{Id: "channelid", Type: model.ChannelTypeOpen}, nil)
+	config.WebhookURLs = "\n" + server.URL + "\n"
+	p.setConfiguration(config)
+	post, err := p.generateDicePost("3d1", "userid", "channelid", "")
+	assert.Nil(t, err)
+	post.Id = "postid"
+	return p, recorder, post
+}
+
+func TestRollWebhook(t *testing.T) {
+	p, recorder, post := initTestWebhook(t, &configuration{WebhookSecret: "secret"}, http.StatusBadGateway)
+
+	p.sendRollWebhooks(post)
+	p.webhooks.Wait()
+
+	assert.Equal(t, 2, recorder.attempts)
+	assert.Equal(t, "application/json", recorder.contentType)
+	var payload webhookPayload
+	assert.Nil(t, json.Unmarshal(recorder.bodies[1], &payload))
+	assert.Equal(t, "postid", payload.PostID)
+	assert.Equal(t, "channelid", payload.ChannelID)
+	assert.Equal(t, 3, payload.Roll.Total)
+	assert.Equal(t, signWebhookPayload("secret", recorder.bodies[1]), recorder.signatures[1])
+}
+
+func TestRollWebhookWithoutSecret(t *testing.T) {
+	p, recorder, post := initTestWebhook(t, &configuration{})
+
+	p.sendRollWebhooks(post)
+	p.webhooks.Wait()
+
+	assert.Equal(t, 1, recorder.attempts)
+	assert.Equal(t, "", recorder.signatures[0])
+}
+
+func TestRollWebhookClientError(t *testing.T) {
+	p, recorder, post := initTestWebhook(t, &configuration{}, http.StatusBadRequest)
+
+	p.sendRollWebhooks(post)
+	p.webhooks.Wait()
+
+	assert.Equal(t, 1, recorder.attempts)
+}
+
+func TestRollWebhookChannelFilter(t *testing.T) {
+	p, recorder, post := initTestWebhook(t, &configuration{WebhookChannelIDs: "otherid, anotherid"})
+	p.sendRollWebhooks(post)
+	p.webhooks.Wait()
+	assert.Equal(t, 0, recorder.attempts)
+
+	p, recorder, post = initTestWebhook(t, &configuration{WebhookChannelIDs: "otherid,channelid"})
+	p.sendRollWebhooks(post)
+	p.webhooks.Wait()
+	assert.Equal(t, 1, recorder.attempts)
+}
+
+func TestRollWebhookWhisperReroll(t *testing.T) {
+	p, recorder, _ := initTestWebhook(t, &configuration{})
+	p.diceBotID = "botid"
+	api := p.API.(*plugintest.API)
+	api.On("GetChannel", "groupid").Return(&model.Channel{Id: "groupid", Type: model.ChannelTypeGroup}, nil)
+	whisperPost, err := p.generateDicePost("3d1", "userid", "groupid", "")
+	assert.Nil(t, err)
+	whisperPost.Id = "whisperid"
+	api.On("GetPost", "whisperid").Return(whisperPost, nil)
+	var post *model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		post = created
+		return created, nil
+	})
+
+	p.ReactionHasBeenAdded(&plugin.Context{}, &model.Reaction{UserId: "userid", PostId: "whisperid", EmojiName: rerollEmoji})
+	p.webhooks.Wait()
+	assert.NotNil(t, post)
+	assert.Equal(t, "groupid", post.ChannelId)
+	assert.Equal(t, 0, recorder.attempts)
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13", signWebhookPayload("secret", []byte("{}")))
+}
+
+func TestRollWebhookStopsOnDeactivate(t *testing.T) {
+	p, recorder, post := initTestWebhook(t, &configuration{}, http.StatusBadGateway, http.StatusBadGateway)
+	webhookRetryDelay = time.Hour
+	p.stopWebhooks = make(chan struct{})
+	p.API.(*plugintest.API).On("LogWarn", "Failed to send the roll to a webhook", "url", mock.Anything, "error", "the plugin was deactivated before retrying: the webhook answered with status 502").Return()
+
+	p.sendRollWebhooks(post)
+	assert.Eventually(t, func() bool {
+		recorder.lock.Lock()
+		defer recorder.lock.Unlock()
+		return recorder.attempts == 1
+	}, time.Second, time.Millisecond)
+	assert.Nil(t, p.OnDeactivate())
+	assert.Equal(t, 1, recorder.attempts)
+}
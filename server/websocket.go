@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// rollResultEvent is the WebSocket event published to the channel members after every public roll.
+// The clients receive it as 'custom_<plugin ID>_roll_result'.
+const rollResultEvent = "roll_result"
+
+// publishRollEvent sends the structured roll of a dice post to the clients watching its channel,
+// for example to animate the dice.
+func (p *Plugin) publishRollEvent(post *model.Post) {
+	data := getRollData(post)
+	if data == nil {
+		return
+	}
+	payload, err := toGenericMap(&webhookPayload{PostID: post.Id, ChannelID: post.ChannelId, Roll: data})
+	if err != nil {
+		p.API.LogError("Failed to encode the roll event", "error", err.Error())
+		return
+	}
+	p.API.PublishWebSocketEvent(rollResultEvent, payload, &model.WebsocketBroadcast{ChannelId: post.ChannelId})
+}
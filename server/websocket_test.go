@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollResultEvent(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetUser", "userid").Return(&model.User{Id: "userid", Nickname: "User"}, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(created *model.Post) (*model.Post, *model.AppError) {
+		created.Id = "postid"
+		return created, nil
+	})
+	var payload map[string]any
+	var broadcast *model.WebsocketBroadcast
+	api.On("PublishWebSocketEvent", rollResultEvent, mock.Anything, mock.Anything).Return().Run(func(args mock.Arguments) {
+		payload = args.Get(1).(map[string]any)
+		broadcast = args.Get(2).(*model.WebsocketBroadcast)
+	})
+	p := Plugin{}
+	p.SetAPI(api)
+
+	_, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll 2d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "channelid", broadcast.ChannelId)
+	assert.Equal(t, "postid", payload["post_id"])
+	assert.Equal(t, "channelid", payload["channel_id"])
+	roll := payload["roll"].(map[string]any)
+	assert.Equal(t, "2d1", roll["query"])
+	assert.Equal(t, float64(2), roll["total"])
+}
+
+func TestNoRollResultEventForPrivateRolls(t *testing.T) {
+	p, api := initTestPlugin()
+	api.On("SendEphemeralPost", "userid", mock.AnythingOfType("*model.Post")).Return(nil)
+
+	_, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{
+		Command:   "/roll private 2d1",
+		UserId:    "userid",
+		ChannelId: "channelid",
+	})
+	assert.Nil(t, err)
+	api.AssertNotCalled(t, "PublishWebSocketEvent", mock.Anything, mock.Anything, mock.Anything)
+}